@@ -0,0 +1,294 @@
+// Package client connects to a game hosted by the server package, speaking
+// the protocol documented there.
+package client
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+
+	"kugo/game"
+	"kugo/mental"
+	"kugo/server"
+)
+
+// Client is a player's connection to a hosted game.
+type Client struct {
+	Seat int
+	// Token is what the server welcomed the client with, and is how it gets
+	// its seat back after losing the connection.
+	Token string
+	conn  net.Conn
+	dec   *json.Decoder
+	enc   *json.Encoder
+	// sendMu keeps the answers to the deal apart from the player's own
+	// messages, as they are sent from whichever goroutine is receiving.
+	sendMu sync.Mutex
+	// peer holds the player's keys when the host deals by mental poker. It
+	// is made for the seat the client is welcomed to.
+	peer *mental.Peer
+	// entropy is the player's share of the seed, and shown every
+	// commitment the host listed when asking for it.
+	entropy game.Seed
+	shown   []game.Contribution
+}
+
+// Dial connects to the game at addr and asks for a seat under the given name.
+// It returns once the server has welcomed the player.
+func Dial(network, addr, name string) (*Client, error) {
+	c, err := Connect(network, addr, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.awaitWelcome(); err != nil {
+		return nil, fmt.Errorf("server refused to seat %s: %w", name, err)
+	}
+	return c, nil
+}
+
+// Connect says hello to the server at addr under the given name, but unlike
+// Dial doesn't wait for a welcome. It is how a lobby is joined, as the
+// welcome only comes once a room's game starts.
+//
+// A player always keeps their own keys, should the host deal by mental
+// poker, and commits to entropy of their own towards the seed otherwise.
+func Connect(network, addr, name string) (*Client, error) {
+	entropy := game.NewSeed()
+	c, err := hello(network, addr, &server.Message{
+		Type:       server.MsgHello,
+		Name:       name,
+		Keys:       true,
+		Commitment: entropy.Commitment(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.entropy = entropy
+	return c, nil
+}
+
+// Rejoin reconnects to the game at addr and takes back the seat the token was
+// given for. Like Dial, it returns once the server has welcomed the player.
+// The keys for a mental poker deal and the entropy for the seed are left
+// behind with the old Client, and have to be handed over with KeepKeys
+// before the next Receive.
+func Rejoin(network, addr, token string) (*Client, error) {
+	c, err := hello(network, addr, &server.Message{Type: server.MsgHello, Token: token, Keys: true})
+	if err != nil {
+		return nil, err
+	}
+	if err := c.awaitWelcome(); err != nil {
+		return nil, fmt.Errorf("server refused to reseat: %w", err)
+	}
+	return c, nil
+}
+
+// Watch connects to the game at addr as a spectator. Like Dial, it returns
+// once the server has welcomed them.
+func Watch(network, addr string) (*Client, error) {
+	c, err := hello(network, addr, &server.Message{Type: server.MsgHello, Watch: true})
+	if err != nil {
+		return nil, err
+	}
+	if err := c.awaitWelcome(); err != nil {
+		return nil, fmt.Errorf("server refused a spectator: %w", err)
+	}
+	return c, nil
+}
+
+func hello(network, addr string, msg *server.Message) (*Client, error) {
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	c := Client{
+		conn: conn,
+		dec:  json.NewDecoder(conn),
+		enc:  json.NewEncoder(conn),
+	}
+	if err := c.send(msg); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &c, nil
+}
+
+// awaitWelcome closes the connection if the next message isn't a welcome.
+func (c *Client) awaitWelcome() error {
+	welcome, err := c.Receive()
+	if err != nil {
+		c.Close()
+		return err
+	}
+	if welcome.Type != server.MsgWelcome {
+		c.Close()
+		return errors.New(welcome.Error)
+	}
+	return nil
+}
+
+// Receive blocks until the next message arrives from the server. Requests
+// for the player's entropy or keys are answered along the way, and never
+// returned. The cards in views the player was dealt face down are filled in
+// from their keys.
+func (c *Client) Receive() (*server.Message, error) {
+	for {
+		var msg server.Message
+		if err := c.dec.Decode(&msg); err != nil {
+			return nil, err
+		}
+		switch msg.Type {
+		case server.MsgWelcome:
+			c.Seat, c.Token = msg.Seat, msg.Token
+		case server.MsgDeal:
+			if err := c.deal(msg.Deal); err != nil {
+				return nil, err
+			}
+			continue
+		case server.MsgReveal:
+			if err := c.reveal(msg.Contributions); err != nil {
+				return nil, err
+			}
+			continue
+		case server.MsgView:
+			c.turnOver(msg.View)
+		}
+		return &msg, nil
+	}
+}
+
+// deal answers a step of a mental poker deal with the player's keys. A step
+// the keys won't take, such as one asking for a card that isn't on top of
+// the deck, is refused.
+func (c *Client) deal(step *server.DealStep) error {
+	if c.peer == nil {
+		c.peer = mental.NewPeer(c.Seat)
+	}
+	msg := server.Message{Type: server.MsgDeal}
+	var err error
+	if step == nil {
+		err = errors.New("no deal step was sent")
+	} else {
+		msg.Deal, err = step.Answer(c.peer)
+	}
+	if err != nil {
+		msg.Error = err.Error()
+	}
+	return c.send(&msg)
+}
+
+// turnOver fills in the cards the host dealt the player face down, which
+// only the player's keys could open.
+func (c *Client) turnOver(view *game.View) {
+	if c.peer == nil || view == nil || view.Seat < 0 || view.Seat >= len(view.Players) {
+		return
+	}
+	for _, hand := range [][]game.Card{view.Players[view.Seat].CardsHeld, view.Returned} {
+		for i, card := range hand {
+			if slot, ok := card.FaceDown(); ok {
+				if dealt, ok := c.peer.Card(slot); ok {
+					hand[i] = dealt
+				}
+			}
+		}
+	}
+}
+
+// reveal sends the host the player's entropy for the seed, as long as the
+// commitment listed for their seat is the one they made.
+func (c *Client) reveal(shown []game.Contribution) error {
+	msg := server.Message{Type: server.MsgReveal}
+	if slices.Contains(shown, game.Contribution{Player: c.Seat, Commitment: c.entropy.Commitment()}) {
+		c.shown = shown
+		msg.Entropy = hex.EncodeToString(c.entropy[:])
+	} else {
+		msg.Error = "my commitment isn't among those listed"
+	}
+	return c.send(&msg)
+}
+
+// CheckContribution checks the record a game ends with against the
+// commitments the host listed when it asked for the player's entropy: that
+// they are all there, unchanged and in order, and that the player's own
+// entropy is among what the seed was made from. It has nothing to check if
+// the player was never asked.
+func (c *Client) CheckContribution(record *game.ShuffleRecord) error {
+	if c.shown == nil {
+		return nil
+	}
+	if len(record.Contributions) != len(c.shown) {
+		return fmt.Errorf("the seed was made from %d contributions, not the %d listed", len(record.Contributions), len(c.shown))
+	}
+	for i, con := range record.Contributions {
+		if con.Player != c.shown[i].Player || con.Commitment != c.shown[i].Commitment {
+			return fmt.Errorf("contribution %d isn't the one listed before the deal", i+1)
+		}
+		if con.Player == c.Seat && con.Entropy != hex.EncodeToString(c.entropy[:]) {
+			return errors.New("the seed wasn't made with your entropy")
+		}
+	}
+	return nil
+}
+
+// KeepKeys takes over the keys and entropy from the player's old
+// connection, so that a deal in progress can go on after they Rejoin, and
+// the seed can still be checked at the end.
+func (c *Client) KeepKeys(from *Client) {
+	c.peer, c.entropy, c.shown = from.peer, from.entropy, from.shown
+}
+
+func (c *Client) send(msg *server.Message) error {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	return c.enc.Encode(msg)
+}
+
+// SendKey passes a key press on to the server as a move.
+func (c *Client) SendKey(key rune) error {
+	return c.send(&server.Message{Type: server.MsgMove, Key: string(key)})
+}
+
+// Say sends a line of chat to everyone in the game.
+func (c *Client) Say(text string) error {
+	return c.send(&server.Message{Type: server.MsgChat, Text: text})
+}
+
+// ListRooms asks a lobby for its rooms, which arrive as a "rooms" message.
+func (c *Client) ListRooms() error {
+	return c.send(&server.Message{Type: server.MsgList})
+}
+
+// CreateRoom opens a room in a lobby and takes a seat in it.
+func (c *Client) CreateRoom(room server.RoomInfo) error {
+	return c.send(&server.Message{Type: server.MsgCreate, Room: &room})
+}
+
+// JoinRoom takes a seat in the lobby room with the given name.
+func (c *Client) JoinRoom(name string) error {
+	return c.send(&server.Message{Type: server.MsgJoin, Room: &server.RoomInfo{Name: name}})
+}
+
+// WatchRoom asks to spectate the game in the lobby room with the given name,
+// which the lobby answers with a welcome.
+func (c *Client) WatchRoom(name string) error {
+	return c.send(&server.Message{Type: server.MsgWatch, Room: &server.RoomInfo{Name: name}})
+}
+
+// LeaveRoom gives up the player's seat in their lobby room.
+func (c *Client) LeaveRoom() error {
+	return c.send(&server.Message{Type: server.MsgLeave})
+}
+
+// Ready tells the lobby whether the player is ready for their room's game to
+// start.
+func (c *Client) Ready(ready bool) error {
+	return c.send(&server.Message{Type: server.MsgReady, Ready: ready})
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
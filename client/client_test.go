@@ -0,0 +1,187 @@
+package client
+
+import (
+	"context"
+	"encoding/hex"
+	"net"
+	"slices"
+	"testing"
+
+	"kugo/game"
+	"kugo/server"
+)
+
+func TestDialAndReceiveView(t *testing.T) {
+	srv, err := server.New(3, 1)
+	if err != nil {
+		t.Fatalf("server.New: %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go srv.Serve(ctx, ln)
+
+	cl, err := Dial("tcp", ln.Addr().String(), "Ann")
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer cl.Close()
+	if cl.Seat != 0 {
+		t.Errorf("seated at %d, want 0", cl.Seat)
+	}
+	msg, err := cl.Receive()
+	if err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	if msg.Type != server.MsgView {
+		t.Fatalf("got %q message, want a view", msg.Type)
+	}
+
+	data := msg.View.DisplayData()
+	if data.Current.Name != "Ann" || data.State.Phase != game.SelectAction {
+		t.Errorf("got %s to %v, want Ann to SelectAction", data.Current, data.State.Phase)
+	}
+	for _, p := range data.AllPlayers {
+		if p.IsLocal != (p.Name == "Ann") {
+			t.Errorf("%s: IsLocal is %t", p.Name, p.IsLocal)
+		}
+		if p.IsLocal && len(p.CardsHeld) != 2 {
+			t.Errorf("%s: holding %d cards, want 2", p.Name, len(p.CardsHeld))
+		}
+		if !p.IsLocal && len(p.CardsHeld) != 0 {
+			t.Errorf("%s: hand visible to Ann", p.Name)
+		}
+	}
+}
+
+func TestDialRefusedWhenFull(t *testing.T) {
+	srv, _ := server.New(3, 1)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go srv.Serve(ctx, ln)
+
+	first, err := Dial("tcp", ln.Addr().String(), "Ann")
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer first.Close()
+	if _, err := Dial("tcp", ln.Addr().String(), "Ben"); err == nil {
+		t.Errorf("second player was seated in a one seat game")
+	}
+}
+
+func TestDialDealsByMentalPoker(t *testing.T) {
+	srv, _ := server.New(3, 2)
+	srv.Mental = true
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go srv.Serve(ctx, ln)
+
+	var clients []*Client
+	for _, name := range []string{"Ann", "Ben"} {
+		cl, err := Dial("tcp", ln.Addr().String(), name)
+		if err != nil {
+			t.Fatalf("Dial %s: %v", name, err)
+		}
+		defer cl.Close()
+		clients = append(clients, cl)
+	}
+	// The deal goes round every player, so both have to be listening for
+	// their part in it.
+	views := make(chan *game.View, len(clients))
+	for _, cl := range clients {
+		go func() {
+			msg, err := cl.Receive()
+			if err != nil || msg.Type != server.MsgView {
+				t.Errorf("seat %d: got %v, %v, want a view", cl.Seat, msg, err)
+				views <- nil
+				return
+			}
+			views <- msg.View
+		}()
+	}
+	for range clients {
+		view := <-views
+		if view == nil {
+			continue
+		}
+		if view.Commitment != "" {
+			t.Errorf("seat %d: committed to a seed the deck wasn't dealt from", view.Seat)
+		}
+		if view.DeckSize != 3*len(game.AllCards)-6 {
+			t.Errorf("seat %d: %d cards left in the deck, want %d", view.Seat, view.DeckSize, 3*len(game.AllCards)-6)
+		}
+		hand := view.Players[view.Seat].CardsHeld
+		if len(hand) != 2 {
+			t.Errorf("seat %d: dealt %v, want two cards", view.Seat, hand)
+		}
+		for _, card := range hand {
+			if !slices.Contains(game.AllCards[:], card) {
+				t.Errorf("seat %d: dealt %d, want a card its keys turned over", view.Seat, card)
+			}
+		}
+	}
+}
+
+func TestDialAddsEntropyToSeed(t *testing.T) {
+	srv, _ := server.New(3, 1)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go srv.Serve(ctx, ln)
+
+	cl, err := Dial("tcp", ln.Addr().String(), "Ann")
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer cl.Close()
+	if msg, err := cl.Receive(); err != nil || msg.Type != server.MsgView {
+		t.Fatalf("got %v, %v, want a view", msg, err)
+	}
+	if len(cl.shown) != 2 {
+		t.Fatalf("asked to reveal against %+v, want the host's and Ann's commitments", cl.shown)
+	}
+
+	// The record the game would end with, with the host's entropy made up.
+	record := func() *game.ShuffleRecord {
+		var r game.ShuffleRecord
+		for _, con := range cl.shown {
+			con.Entropy = hex.EncodeToString(cl.entropy[:])
+			r.Contributions = append(r.Contributions, con)
+		}
+		return &r
+	}
+	tests := []struct {
+		name   string
+		tamper func(r *game.ShuffleRecord)
+		ok     bool
+	}{
+		{"unchanged", func(*game.ShuffleRecord) {}, true},
+		{"entropy", func(r *game.ShuffleRecord) { r.Contributions[1].Entropy = r.Contributions[0].Commitment }, false},
+		{"commitment", func(r *game.ShuffleRecord) { r.Contributions[0].Commitment = r.Contributions[1].Commitment }, false},
+		{"dropped", func(r *game.ShuffleRecord) { r.Contributions = r.Contributions[:1] }, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := record()
+			tc.tamper(r)
+			if err := cl.CheckContribution(r); (err == nil) != tc.ok {
+				t.Errorf("CheckContribution: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	dis "kugo/display"
+	"kugo/server"
+)
+
+// RunCommand runs one of kugo's subcommands, such as "serve". Running kugo
+// with no subcommand starts a local game instead.
+func RunCommand(name string, args []string) error {
+	switch name {
+	case "serve":
+		return runServe(args)
+	case "join":
+		return runJoin(args)
+	case "ssh-serve":
+		return runSSHServe(args)
+	case "lobby":
+		return runLobby(args)
+	case "host":
+		return runHost(args)
+	case "seat":
+		return runSeat(args)
+	case "verify":
+		return runVerify(args)
+	case "web":
+		return runWeb(args)
+	case "api":
+		return runAPI(args)
+	default:
+		return fmt.Errorf("unknown command %q", name)
+	}
+}
+
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	port := flags.Int("port", 7777, "TCP port to listen on")
+	numPlayers := flags.Int("players", 4, "total number of seats, 3 to 6")
+	numHumans := flags.Int("humans", 2, "number of seats for remote players; bots take the rest")
+	grace := flags.Duration("grace", server.DefaultGrace, "how long a dropped player has to reconnect before a bot stands in")
+	reveal := flags.Bool("reveal", false, "let spectators see every hand, after a delay")
+	revealDelay := flags.Duration("reveal-delay", server.DefaultRevealDelay, "how far behind the game spectators are kept when hands are revealed")
+	name := flags.String("name", defaultGameName(), "what to call the game when announcing it")
+	doAnnounce := flags.Bool("announce", true, "announce the game to the local network")
+	mental := flags.Bool("mental", false, "have the players deal the deck between themselves by mental poker")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	srv, err := server.New(*numPlayers, *numHumans)
+	if err != nil {
+		return err
+	}
+	srv.Grace = *grace
+	srv.Reveal, srv.RevealDelay = *reveal, *revealDelay
+	srv.Mental = *mental
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *doAnnounce {
+		announce(ctx, ln, false, func() []server.RoomInfo {
+			info := srv.Info()
+			info.Name = *name
+			return []server.RoomInfo{info}
+		})
+	}
+	fmt.Printf("kugo is waiting for %d players on %s\n", *numHumans, ln.Addr())
+	return srv.Serve(ctx, ln)
+}
+
+// defaultGameName is what a hosted game is announced as if it isn't named.
+func defaultGameName() string {
+	host, err := os.Hostname()
+	if err != nil {
+		return "kugo"
+	}
+	return host
+}
+
+// defaultSocket is where host and seat meet if no socket is given.
+var defaultSocket = filepath.Join(os.TempDir(), "kugo.sock")
+
+func runHost(args []string) error {
+	flags := flag.NewFlagSet("host", flag.ContinueOnError)
+	socket := flags.String("socket", defaultSocket, "Unix domain socket to listen on")
+	numPlayers := flags.Int("players", 4, "total number of seats, 3 to 6")
+	numHumans := flags.Int("humans", 2, "number of seats for players at this machine; bots take the rest")
+	grace := flags.Duration("grace", server.DefaultGrace, "how long a dropped player has to reconnect before a bot stands in")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	srv, err := server.New(*numPlayers, *numHumans)
+	if err != nil {
+		return err
+	}
+	srv.Grace = *grace
+	ln, err := server.ListenUnix(*socket)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	fmt.Printf("kugo is waiting for %d players on %s\n", *numHumans, *socket)
+	fmt.Printf("players take a seat with: kugo seat --socket %s\n", *socket)
+	return srv.Serve(ctx, ln)
+}
+
+func runSeat(args []string) error {
+	flags := flag.NewFlagSet("seat", flag.ContinueOnError)
+	socket := flags.String("socket", defaultSocket, "Unix domain socket the game is hosted on")
+	name := flags.String("name", "", "name to play under; asked for if not given")
+	watch := flags.Bool("watch", false, "spectate the game instead of playing")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	return dis.WrapDisplay(func() error {
+		if *watch {
+			return WatchLoop("unix", *socket)
+		}
+		return JoinLoop("unix", *socket, *name)
+	})
+}
+
+func runLobby(args []string) error {
+	flags := flag.NewFlagSet("lobby", flag.ContinueOnError)
+	port := flags.Int("port", lobbyPort, "TCP port to listen on")
+	doAnnounce := flags.Bool("announce", true, "announce the lobby to the local network")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	lobby := server.NewLobby()
+	if *doAnnounce {
+		announce(ctx, ln, true, lobby.Rooms)
+	}
+	fmt.Printf("kugo lobby is open on %s\n", ln.Addr())
+	err = lobby.Serve(ctx, ln)
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+func runSSHServe(args []string) error {
+	flags := flag.NewFlagSet("ssh-serve", flag.ContinueOnError)
+	port := flags.Int("port", 2222, "TCP port to listen on")
+	numPlayers := flags.Int("players", 4, "total number of seats, 3 to 6")
+	numHumans := flags.Int("humans", 2, "number of seats for ssh players; bots take the rest")
+	hostKey := flags.String("host-key", "", "private key file to identify the server; a throwaway key is made if not given")
+	grace := flags.Duration("grace", server.DefaultGrace, "how long a dropped player has to reconnect before a bot stands in")
+	reveal := flags.Bool("reveal", false, "let spectators see every hand, after a delay")
+	revealDelay := flags.Duration("reveal-delay", server.DefaultRevealDelay, "how far behind the game spectators are kept when hands are revealed")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	signer, err := loadHostKey(*hostKey)
+	if err != nil {
+		return err
+	}
+	// Players are only asked for a name, which ssh sends as the user.
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+
+	srv, err := server.New(*numPlayers, *numHumans)
+	if err != nil {
+		return err
+	}
+	srv.Grace = *grace
+	srv.Reveal, srv.RevealDelay = *reveal, *revealDelay
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	fmt.Printf("kugo is waiting for %d players on %s\n", *numHumans, ln.Addr())
+	fmt.Printf("host key fingerprint: %s\n", ssh.FingerprintSHA256(signer.PublicKey()))
+	fmt.Printf("players join with: ssh -p %d NAME@HOST\n", *port)
+	return srv.ServeSSH(ctx, ln, config)
+}
+
+func runWeb(args []string) error {
+	flags := flag.NewFlagSet("web", flag.ContinueOnError)
+	addr := flags.String("addr", "127.0.0.1:8080", "address to serve the web client on")
+	numPlayers := flags.Int("players", 4, "total number of seats, 3 to 6")
+	numHumans := flags.Int("humans", 1, "number of seats for browser players; bots take the rest")
+	grace := flags.Duration("grace", server.DefaultGrace, "how long a dropped player has to reconnect before a bot stands in")
+	reveal := flags.Bool("reveal", false, "let spectators see every hand, after a delay")
+	revealDelay := flags.Duration("reveal-delay", server.DefaultRevealDelay, "how far behind the game spectators are kept when hands are revealed")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	srv, err := server.New(*numPlayers, *numHumans)
+	if err != nil {
+		return err
+	}
+	srv.Grace = *grace
+	srv.Reveal, srv.RevealDelay = *reveal, *revealDelay
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	fmt.Printf("kugo is waiting for %d players at http://%s/\n", *numHumans, ln.Addr())
+	return srv.ServeWeb(ctx, ln)
+}
+
+func runAPI(args []string) error {
+	flags := flag.NewFlagSet("api", flag.ContinueOnError)
+	addr := flags.String("addr", "127.0.0.1:8081", "address to serve the API on")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	fmt.Printf("kugo is serving its API at http://%s/\n", ln.Addr())
+	return server.NewAPI(ctx).Serve(ctx, ln)
+}
+
+// loadHostKey reads the server's private key from path, or makes a new one
+// for this run if path is empty.
+func loadHostKey(path string) (ssh.Signer, error) {
+	if path == "" {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return ssh.NewSignerFromKey(key)
+	}
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ssh.ParsePrivateKey(pem)
+}
+
+func runJoin(args []string) error {
+	// The address comes first, so pull it off before the flags are parsed.
+	var addr string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		addr, args = args[0], args[1:]
+	}
+	flags := flag.NewFlagSet("join", flag.ContinueOnError)
+	name := flags.String("name", "", "name to play under; asked for if not given")
+	lobby := flags.Bool("lobby", false, "the address is a lobby rather than a single game")
+	watch := flags.Bool("watch", false, "spectate the game instead of playing, or with --lobby the room picked")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if addr == "" {
+		// Without an address, look for a game on the local network.
+		return dis.WrapDisplay(func() error {
+			return DiscoverLoop(*name, *watch)
+		})
+	}
+	return dis.WrapDisplay(func() error {
+		if *watch && !*lobby {
+			return WatchLoop("tcp", addr)
+		}
+		if *lobby {
+			if *name == "" {
+				var err error
+				if *name, err = GetPlayerName(); err != nil {
+					return err
+				}
+			}
+			return LobbyLoop("tcp", addr, *name, nil, *watch)
+		}
+		return JoinLoop("tcp", addr, *name)
+	})
+}
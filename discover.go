@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	dis "kugo/display"
+	"kugo/game"
+	inp "kugo/input"
+	"kugo/server"
+)
+
+// discoverFor is how long to listen for games before listing them. It is
+// long enough to hear every host announce itself at least once.
+const discoverFor = 2 * time.Second
+
+// announce tells the local network about the game or lobby listening on ln
+// until ctx is done.
+func announce(ctx context.Context, ln net.Listener, lobby bool, rooms func() []server.RoomInfo) {
+	port := ln.Addr().(*net.TCPAddr).Port
+	go func() {
+		err := server.Announce(ctx, server.BroadcastAddr, func() server.Announcement {
+			return server.Announcement{Port: port, Lobby: lobby, Rooms: rooms()}
+		})
+		if err != nil && !errors.Is(err, context.Canceled) {
+			game.Debugf("announcing: %v", err)
+		}
+	}()
+}
+
+// DiscoverLoop lists the games announced on the local network, lets the
+// player pick one, and joins it as if its address had been given.
+func DiscoverLoop(name string, watch bool) error {
+	conn, err := net.ListenPacket("udp4", fmt.Sprintf(":%d", server.DiscoveryPort))
+	if err != nil {
+		return fmt.Errorf("can't listen for games: %w", err)
+	}
+	defer conn.Close()
+
+	display := dis.NewDisplay(make(chan error))
+	for {
+		display.DrawMessage("Looking for games on your network...")
+		games, err := server.Discover(context.Background(), conn, discoverFor)
+		if err != nil {
+			return err
+		}
+		var lines []string
+		for i, g := range games {
+			lines = append(lines, fmt.Sprintf("%d. %s", i+1, describeGame(g)))
+		}
+		if len(lines) == 0 {
+			lines = append(lines, "No games found.")
+		}
+		display.DrawList("Games on your network", lines, []string{
+			"press a game's number to join it, or 'r' to look again",
+			"press 'q' at any time to quit",
+		})
+
+		// Nothing else is reading the keyboard yet, so read it here rather
+		// than leave a reader behind to steal keys from the game.
+		key, err := readKey()
+		if err != nil {
+			return err
+		}
+		switch {
+		case key == 'q':
+			return fmt.Errorf("User Quit")
+		case key >= '1' && key <= '9' && int(key-'1') < len(games):
+			conn.Close()
+			return joinDiscovered(games[key-'1'], name, watch)
+		}
+	}
+}
+
+func joinDiscovered(g server.Announcement, name string, watch bool) error {
+	switch {
+	case g.Lobby:
+		if name == "" {
+			var err error
+			if name, err = GetPlayerName(); err != nil {
+				return err
+			}
+		}
+		return LobbyLoop("tcp", g.Addr, name, nil, watch)
+	case watch:
+		return WatchLoop("tcp", g.Addr)
+	default:
+		return JoinLoop("tcp", g.Addr, name)
+	}
+}
+
+func readKey() (rune, error) {
+	ev, err := inp.Stdin().Next()
+	return ev.Key, err
+}
+
+// describeGame sums up an announced game on one line.
+func describeGame(g server.Announcement) string {
+	if g.Lobby {
+		var rooms []string
+		for _, room := range g.Rooms {
+			status := fmt.Sprintf("%d/%d seated", len(room.Members), room.Players)
+			if room.Started {
+				status = "playing"
+			}
+			rooms = append(rooms, fmt.Sprintf("%s %s", room.Name, status))
+		}
+		if len(rooms) == 0 {
+			rooms = append(rooms, "no rooms yet")
+		}
+		return fmt.Sprintf("Lobby at %s: %s", g.Addr, strings.Join(rooms, "; "))
+	}
+	if len(g.Rooms) == 0 {
+		return g.Addr
+	}
+	// A single game's bots are there for good, so only the rest are open.
+	room := &g.Rooms[0]
+	status := fmt.Sprintf("%d open seats", room.Players-len(room.Members)-len(room.Bots))
+	if room.Started {
+		status = "playing"
+	}
+	return fmt.Sprintf("%-20s %-14s %s, at %s", room.Name, status, describeRules(room), g.Addr)
+}
@@ -3,18 +3,21 @@ package display
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"slices"
 	"strings"
-	"time"
+	"sync"
 
 	"golang.org/x/term"
 
 	"kugo/game"
+	inp "kugo/input"
 )
 
 const (
 	Reset   = "\033[2J\033[1;1H\033[?25l"
-	Restore = "\033[2J\033[1;1H\033[?25h"
+	Restore = MouseOff + "\033[2J\033[1;1H\033[?25h"
 )
 
 // Error handling
@@ -27,212 +30,510 @@ func (d *Display) RecoverPanic() {
 
 // Terminal Functions
 
-func MoveCursor(row int, col int) {
-	fmt.Printf("\033[%d;%dH", row, col)
-}
-
-func AddStr(row, col int, str string) {
-	MoveCursor(row, col)
-	fmt.Print(str)
-}
-
 func WrapDisplay(f func() error) error {
 	// Put terminal in raw mode
 	oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
 	// Restore the user's terminal on exit
-	defer RestoreDisplay(oldState)
-	if err != nil {
-		return err
-	}
-	fmt.Print(Reset)
-	err = f() // <-- Where the game is actually running
+	defer term.Restore(int(os.Stdin.Fd()), oldState)
 	if err != nil {
 		return err
 	}
-	return nil
+	return WrapOutput(os.Stdout, f)
 }
 
-func RestoreDisplay(s *term.State) {
-	fmt.Print(Restore)
-	term.Restore(int(os.Stdin.Fd()), s)
+// WrapOutput clears the terminal behind out and hides the cursor while f runs,
+// then puts them back. Unlike WrapDisplay it leaves raw mode to whoever owns
+// the terminal, such as an SSH client.
+func WrapOutput(out io.Writer, f func() error) error {
+	fmt.Fprint(out, Reset)
+	defer fmt.Fprint(out, Restore)
+	return f() // <-- Where the game is actually running
 }
 
+// Display draws the game to a terminal. The game hands it what to show
+// through the Update methods, from whichever goroutine it likes, and
+// DrawDisplay draws each change as it comes.
 type Display struct {
-	allPlayers    []*game.Player
-	activePlayers []*game.Player
-	validTargets  []*game.Player
-	current       *game.Player
-	victor        *game.Player
-	actionLog     *game.ActionLog
-	State         game.State
-	clock         *time.Ticker
+	// frame is what is being drawn. Only the goroutine drawing, which holds
+	// drawMu, touches it or anything below it.
+	frame
+	drawMu        sync.Mutex
 	row           int
 	chanErr       chan error
+	out           io.Writer
 	builder		  *strings.Builder
-	Selection	  int
+	frameWidth    int
+	frameHeight   int
+	panel         panel
+	logRows       int
+	chatBelow     bool
+	follow        bool
+	front         grid
+	back          grid
+	hits          []hit
+	// latest is the newest frame, which frames holds until it is drawn.
+	mu            sync.Mutex
+	latest        frame
+	frames        chan frame
+	// clickable is where the frame on screen can be clicked.
+	clickable     []hit
+}
+
+// frame is everything a screen of the game is drawn from. The Update methods
+// each make a new one from the last, and none is changed once made, so the
+// game can carry on while one is being drawn.
+type frame struct {
+	model      *Model
+	cursor     int
+	state      game.State
+	names      []string
+	cardCounts []game.CardCount
+	spectating bool
+	revealed   bool
+	thoughts   []string
+	hint       []game.Option
+	showHint   bool
+	curtain    string
+	note       string
+	showChat   bool
+	chat       []string
+	chatTyping bool
+	chatText   string
+	emotes     []string
+	width      int
+	height     int
+}
+
+// publish makes a new frame from the latest with change applied, and hands
+// it to DrawDisplay. Only the newest frame waits to be drawn, so a Display
+// that is falling behind skips straight to it.
+func (d *Display) publish(change func(f *frame)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	change(&d.latest)
+	d.send()
+}
+
+// send hands the latest frame to DrawDisplay. The caller must hold d.mu.
+func (d *Display) send() {
+	select {
+	case <-d.frames:
+	default:
+	}
+	d.frames <- d.latest
+}
+
+// current is the latest frame, for screens drawn straight away.
+func (d *Display) current() frame {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.latest
 }
 
 func NewDisplay(chanErr chan error) *Display {
-	var fps float64 = 1000.0 / 24
+	d := NewDisplayTo(os.Stdout, chanErr)
+	d.follow = true
+	d.fitTerminal()
+	return d
+}
+
+// NewDisplayTo creates a Display that draws to out rather than stdout.
+func NewDisplayTo(out io.Writer, chanErr chan error) *Display {
 	builder := new(strings.Builder)
-	return &Display{clock: time.NewTicker(time.Duration(fps) * time.Millisecond), chanErr: chanErr, builder: builder}
+	d := Display{chanErr: chanErr, out: out, builder: builder, frames: make(chan frame, 1)}
+	return &d
 }
 
 func (d *Display) buildString(row, col int, str string) {
-	outStr := fmt.Sprintf("\033[%d;%dH%s", row, col, str)
-	_, err := d.builder.WriteString(outStr)
-	if err != nil {
-		d.chanErr <- err
+	if d.frameHeight > 0 && row > d.frameHeight {
+		return
+	}
+	if d.panel.width > 0 {
+		str = clip(str, d.panel.width-max(col-1, 0))
 	}
+	col += d.panel.left
+	d.back.put(row, col, str)
 }
 
+// Blit sends the frame that has been drawn to the terminal. Only the cells
+// that differ from the last frame are written, so nothing flickers.
 func (d *Display) Blit() {
-	fmt.Print(d.builder.String())
+	d.builder.Reset()
+	diff(d.builder, d.front, d.back)
+	d.front = d.back
+	d.mu.Lock()
+	d.clickable = d.hits
+	d.mu.Unlock()
+	if d.builder.Len() > 0 {
+		fmt.Fprint(d.out, d.builder.String())
+	}
 }
 
 // Draw Functions
 func (d *Display) resetScreen() {
-	d.builder.Reset()
-	d.builder.WriteString("\033[2J\033[1;1H")
+	d.back = grid{}
+	d.hits = nil
 	d.row = 1
+	width, height := d.width, d.height
+	if width != d.frameWidth || height != d.frameHeight {
+		// Terminals rewrap what is on screen when resized, so start afresh.
+		d.front = nil
+	}
+	d.frameWidth, d.frameHeight = width, height
+	d.panel = panel{width: d.frameWidth}
+	d.logRows = 0
 }
 
-func (d *Display) checkAudience() bool {
-	for _, p := range d.activePlayers {
-		if !p.IsLocal {
-			continue
-		}
-		return true
+// DrawMenuScreen shows a menu outside a game, such as the main menu, with
+// the keys that work everywhere under it. The cursor stays where Navigate
+// put it unless the menu has a different number of options.
+func (d *Display) DrawMenuScreen(prompt string, options []MenuOption) {
+	d.mu.Lock()
+	if d.latest.model == nil || len(d.latest.model.Options) != len(options) {
+		d.latest.cursor = 0
 	}
-	d.buildString(d.row, 5, "Biding your time...")
-	d.row++
-	return false
+	d.latest.model = &Model{Prompt: prompt, Options: options}
+	d.mu.Unlock()
+
+	d.drawMu.Lock()
+	defer d.drawMu.Unlock()
+	d.frame = d.current()
+	d.resetScreen()
+	d.drawHeader()
+	d.drawMenu()
+	d.drawMenuHelp()
+	d.Blit()
+}
+
+// DrawMessage clears the screen and shows a single line under the header, for
+// when there is no game to draw yet.
+func (d *Display) DrawMessage(msg string) {
+	d.drawMu.Lock()
+	defer d.drawMu.Unlock()
+	d.frame = d.current()
+	d.resetScreen()
+	d.drawHeader()
+	d.buildString(d.row, 1, msg)
+	d.Blit()
+	d.redraw()
 }
 
-func (d *Display) DrawMenuScreen(selection int) {
+// DrawList shows a title over a list of lines, with help for the keys that
+// work underneath. It is for screens outside a game, such as the lobby.
+func (d *Display) DrawList(title string, lines, help []string) {
+	d.drawMu.Lock()
+	defer d.drawMu.Unlock()
+	d.frame = d.current()
 	d.resetScreen()
 	d.drawHeader()
-	d.Selection = selection
-	d.State = game.State{game.MainMenu, game.NoAction}
-	d.DrawMainMenu()
+	d.buildString(d.row, 3, title)
+	d.row += 2
+	for _, line := range lines {
+		d.buildString(d.row, 5, line)
+		d.row++
+	}
+	d.row++
+	for _, line := range help {
+		d.buildString(d.row, 3, line)
+		d.row++
+	}
 	d.Blit()
+	d.redraw()
+}
+
+// redraw asks DrawDisplay to draw the latest frame again, over a screen drawn
+// straight away.
+func (d *Display) redraw() {
+	d.publish(func(*frame) {})
 }
 
+// DrawDisplay draws each frame as the Update methods make it, until ctx is
+// done. Only the cells that differ from the last frame are sent.
 func (d *Display) DrawDisplay(ctx context.Context) {
 	defer d.RecoverPanic()
+	if d.follow {
+		d.followTerminal(ctx)
+	}
+	d.reportMouse(ctx)
 	for {
-		switch d.State.Phase {
-		case game.EndGame:
-			d.resetScreen()
-			d.drawHeader()
-			d.drawVictoryScreen()
-			d.Blit()
-			<-d.clock.C
-		default:
-			d.resetScreen()
-			d.drawHeader()
-			d.drawPlayers()
-			d.drawActionLog()
-			d.drawLocalHand()
-			d.drawMenu()
-			d.Blit()
-			<-d.clock.C
+		select {
+		case <-ctx.Done():
+			return
+		case f := <-d.frames:
+			d.draw(f)
 		}
 	}
 }
 
+func (d *Display) draw(f frame) {
+	d.drawMu.Lock()
+	defer d.drawMu.Unlock()
+	d.frame = f
+	switch {
+	case d.curtain != "":
+		d.resetScreen()
+		d.drawHeader()
+		d.buildString(d.row, 5, d.curtain)
+		d.Blit()
+	case d.model == nil:
+		// There is no game to draw yet.
+	case d.state.Phase == game.EndGame:
+		d.resetScreen()
+		d.drawHeader()
+		d.drawVictoryScreen()
+		d.Blit()
+	default:
+		d.resetScreen()
+		d.drawHeader()
+		d.drawTable()
+		d.Blit()
+	}
+}
+
+// UpdateDisplay sets the game to be drawn. Everything needed from info is
+// copied, so the game is free to change it afterwards.
 func (d *Display) UpdateDisplay(info *game.DisplayData) {
-	d.allPlayers = info.AllPlayers
-	d.activePlayers = info.ActivePlayers
-	d.validTargets = info.ValidTargets
-	d.current = info.Current
-	d.actionLog = info.ActionLog
-	d.State = info.State
-	d.Selection = info.Selection
-	if d.State.Phase != game.EndGame {
-		return
+	model := NewModel(info)
+	names := make([]string, len(info.AllPlayers))
+	for _, p := range info.AllPlayers {
+		names[p.Index] = p.Name
 	}
-	for _, p := range d.allPlayers {
-		if !p.IsAlive() {
-			continue
+	counts := slices.Clone(info.CardCounts)
+	d.publish(func(f *frame) {
+		if f.model == nil || !slices.Equal(f.model.Options, model.Options) {
+			// A new menu starts with its first option picked.
+			f.cursor = 0
 		}
-		d.victor = p
-	}
+		f.model = model
+		f.state = info.State
+		f.names = names
+		f.cardCounts = counts
+		f.spectating = info.Spectating
+		f.revealed = info.Revealed
+	})
+}
+
+// UpdateThoughts sets the bot rationales shown in the thoughts panel. A nil
+// slice hides the panel.
+func (d *Display) UpdateThoughts(thoughts []string) {
+	thoughts = slices.Clone(thoughts)
+	d.publish(func(f *frame) { f.thoughts = thoughts })
+}
+
+// DrawCurtain hides the table behind msg until it is called again with an
+// empty one. It is for passing a shared terminal between players.
+func (d *Display) DrawCurtain(msg string) {
+	d.publish(func(f *frame) { f.curtain = msg })
+}
+
+// UpdateNote sets a line shown under the victory screen, such as whether the
+// deal checked out.
+func (d *Display) UpdateNote(note string) {
+	d.publish(func(f *frame) { f.note = note })
+}
+
+// UpdateChat sets the chat lines shown beside the action log, and shows the
+// panel if it isn't already.
+func (d *Display) UpdateChat(lines []string) {
+	lines = slices.Clone(lines)
+	d.publish(func(f *frame) {
+		f.chat = lines
+		f.showChat = true
+	})
+}
+
+// UpdateChatEntry shows what the player is typing, or the emote menu if
+// emotes is not nil.
+func (d *Display) UpdateChatEntry(typing bool, text string, emotes []string) {
+	emotes = slices.Clone(emotes)
+	d.publish(func(f *frame) {
+		f.chatTyping = typing
+		f.chatText = text
+		f.emotes = emotes
+	})
+}
+
+// UpdateHint shows the advisor's options for the local player, best first.
+func (d *Display) UpdateHint(options []game.Option) {
+	options = slices.Clone(options)
+	d.publish(func(f *frame) {
+		f.hint = options
+		f.showHint = true
+	})
+}
+
+// ClearHint hides the advisor panel, which should happen whenever the game
+// moves on and the advice goes stale.
+func (d *Display) ClearHint() {
+	d.publish(func(f *frame) {
+		f.hint = nil
+		f.showHint = false
+	})
 }
 
 func (d *Display) drawHeader() {
-	d.buildString(d.row, 16, "=== KUGO ===")
+	col := 16
+	if d.frameWidth >= columnsWidth {
+		col = (d.frameWidth - 12) / 2
+	}
+	d.buildString(d.row, col, "=== KUGO ===")
 	d.row += 2
 }
 
 func (d *Display) drawPlayers() {
-	for _, player := range d.allPlayers {
+	// While picking a target, clicking a player picks them.
+	targets := make(map[string]int)
+	if d.state.Phase == game.SelectTarget {
+		for _, o := range d.model.Options {
+			targets[o.Label] = o.Key
+		}
+	}
+	for _, row := range d.model.Players {
 		marker := "    "
 		coinString := "\033[31m~ELIMINATED~\033[0m"
-		currentIdx := d.current.Index
-		if player.Index == currentIdx {
+		if row.Current {
 			marker = ">>> "
 		}
-		handString := getHandString(player)
-		if player.IsAlive() {
-			coinString = fmt.Sprintf("%2d", player.Coins)
+		if row.Alive {
+			coinString = fmt.Sprintf("%2d", row.Coins)
+		}
+		// Pad by hand, as %-12s would count runes rather than columns.
+		name := row.Name + strings.Repeat(" ", max(12-inp.StringWidth(row.Name), 0))
+		playerString := fmt.Sprintf("%s%s%s      %s", marker, name, coinString, row.Hand)
+		if !row.Local {
+			playerString += "   " + row.Claims
 		}
-		playerString := fmt.Sprintf("%s%-12s%s      %s", marker, player.Name, coinString, handString)
 		d.buildString(d.row, 1, playerString)
+		if key, ok := targets[row.Name]; ok {
+			d.addHit(d.row, 1, playerString, key)
+		}
 		d.row++
 	}
 	d.row++
 }
 
+// drawActionLog draws the latest entries in the log, as many as there is room
+// for, with the chat panel beside or under it.
 func (d *Display) drawActionLog() {
-	if len(d.actionLog.Items) == 0 {
+	start := d.row
+	chatEnd := start
+	if d.chatBeside() {
+		chatEnd = d.drawChat(start, chatColumn)
+	}
+	log := d.model.Log
+	if d.logRows > 0 && len(log) > d.logRows {
+		log = log[len(log)-d.logRows:]
+	}
+	if len(log) > 0 {
+		for _, msg := range log {
+			d.buildString(d.row, 1, msg)
+			d.row++
+		}
+		d.row++
+	}
+	d.row = max(d.row, chatEnd)
+	if !d.chatBeside() {
+		d.row = d.drawChat(d.row, 1)
+	}
+}
+
+// chatColumn is where the chat panel starts, clear of the action log.
+const chatColumn = 72
+
+// drawChat draws the chat panel at row and col, by the action log, and
+// returns the row after it. It is only drawn in networked games.
+func (d *Display) drawChat(row, col int) int {
+	if !d.showChat {
+		return row
+	}
+	d.buildString(row, col, "Chat ('c' to talk, 'e' for emotes):")
+	row++
+	for _, line := range d.chat {
+		d.buildString(row, col+2, line)
+		row++
+	}
+	switch {
+	case d.chatTyping:
+		d.buildString(row, col, fmt.Sprintf("> %s_", d.chatText))
+		row++
+	case d.emotes != nil:
+		for i, emote := range d.emotes {
+			d.buildString(row, col, fmt.Sprintf("%d. %s", i+1, emote))
+			row++
+		}
+	}
+	return row + 1
+}
+
+func (d *Display) drawBotThoughts() {
+	if d.thoughts == nil {
 		return
 	}
-	for _, msg := range d.actionLog.Items {
-		d.buildString(d.row, 1, msg)
+	d.buildString(d.row, 1, "Bot thoughts:")
+	d.row++
+	for _, msg := range d.thoughts {
+		d.buildString(d.row, 3, msg)
 		d.row++
 	}
 	d.row++
 }
 
 func (d *Display) drawLocalHand() {
-	var pHand string
-	for _, p := range d.allPlayers {
-		if !p.IsLocal {
+	for _, row := range d.model.Players {
+		if !row.Local {
 			continue
 		}
-		switch len(p.CardsHeld) {
-		case 1:
-			pHand = fmt.Sprintf("Your hand: [%s]", p.CardsHeld[0])
-		case 2:
-			pHand = fmt.Sprintf(
-				"Your hand: [%s | %s]",
-				p.CardsHeld[0],
-				p.CardsHeld[1],
-			)
-		case 3:
-			pHand = fmt.Sprintf(
-				"Your hand: [%s | %s | %s]",
-				p.CardsHeld[0],
-				p.CardsHeld[1],
-				p.CardsHeld[2],
-			)
-		case 4:
-			pHand = fmt.Sprintf(
-				"Your hand: [%s | %s | %s | %s]",
-				p.CardsHeld[0],
-				p.CardsHeld[1],
-				p.CardsHeld[2],
-				p.CardsHeld[3],
-			)
-		}
-		d.buildString(d.row, 5, pHand)
+		d.buildString(d.row, 5, "Your hand: "+joinCards(d.model.Hand))
+		d.addCardHits(d.row, 5+len("Your hand: ["))
 		d.row += 2
 		return
 	}
 }
 
-func getHandString(p *game.Player) string {
+// drawCardCounts shows, for each role, how many copies the local player can't
+// account for and the chance each opponent is holding at least one.
+func (d *Display) drawCardCounts() {
+	if len(d.cardCounts) == 0 {
+		return
+	}
+	var opponents []int
+	for i := range d.names {
+		if _, ok := d.cardCounts[0].Odds[i]; ok {
+			opponents = append(opponents, i)
+		}
+	}
+	header := fmt.Sprintf("%-12s%6s", "Unseen", "Left")
+	for _, i := range opponents {
+		header += fmt.Sprintf("%9.8s", d.names[i])
+	}
+	d.buildString(d.row, 5, header)
+	d.row++
+	for _, count := range d.cardCounts {
+		// Pad by hand as the card's colour codes would throw off %-12s.
+		name := count.Card.String() + strings.Repeat(" ", 12-len(count.Card.Name()))
+		line := fmt.Sprintf("%s%6d", name, count.Unseen)
+		for _, i := range opponents {
+			line += fmt.Sprintf("%8.0f%%", 100*count.Odds[i])
+		}
+		d.buildString(d.row, 5, line)
+		d.row++
+	}
+	d.row++
+}
+
+// getHandString shows a player's lost cards, and their held cards too when
+// revealed to a spectator. Otherwise held cards are hidden.
+func getHandString(p *game.Player, revealed bool) string {
+	if revealed {
+		var cards []string
+		for _, c := range p.CardsLost {
+			cards = append(cards, c.Short())
+		}
+		for _, c := range p.CardsHeld {
+			cards = append(cards, "\033[1m"+c.Short()+"\033[0m")
+		}
+		return fmt.Sprintf("[%s]", strings.Join(cards, " | "))
+	}
 	if len(p.CardsLost) == 2 {
 		return fmt.Sprintf("[%s | %s]", p.CardsLost[0].Short(), p.CardsLost[1].Short())
 	}
@@ -242,129 +543,73 @@ func getHandString(p *game.Player) string {
 	return fmt.Sprintf("[%s | ???]", p.CardsLost[0].Short())
 }
 
-func (d *Display) drawMenu() {
-	if !d.checkAudience() {
-		return
-	}
-	switch d.State.Phase {
-	case game.SelectAction:
-		d.drawActionMenu()
-	case game.SelectTarget:
-		d.drawTargetMenu()
-	case game.MakeChallenge, game.ChallengeBlock:
-		d.drawChallengeMenu()
-	case game.MakeBlock:
-		d.drawBlockMenu()
-	case game.ChallengeReveal, game.BlockReveal:
-		d.drawRevealMenu()
-	case game.ChallengeLoss, game.BlockLoss:
-		d.drawLossMenu()
-	case game.ResolveAction:
-		if d.State.Action == game.Assassinate || d.State.Action == game.Coup {
-			d.drawLossMenu()
-			return
+// getClaimString lists the roles a player has claimed this game. A count
+// follows roles claimed more than once, then a tick if a reveal has backed
+// the claim up and a cross if it has been exposed as a bluff.
+func getClaimString(p *game.Profile) string {
+	var parts []string
+	for _, card := range game.AllCards {
+		if p.Claims[card] == 0 {
+			continue
 		}
-	case game.ExchangeMiddle:
-		d.drawReturnTwo()
-	case game.ExchangeFinal:
-		d.drawReturnOne()
-	default:
-		panic("Unreachable code! (DrawMenu)")
+		part := card.Short()
+		if p.Claims[card] > 1 {
+			part += fmt.Sprintf("x%d", p.Claims[card])
+		}
+		if p.Proven[card] > 0 {
+			part += "\033[32m✓\033[0m"
+		}
+		if p.Bluffs[card] > 0 {
+			part += "\033[31m✗\033[0m"
+		}
+		parts = append(parts, part)
 	}
-}
-
-func (d *Display) drawActionMenu() {
-	d.buildString(d.row, 5, "[1] Income (+1 coin)")
-	d.row++
-	d.buildString(d.row, 5, fmt.Sprintf("[2] Foreign Aid (+2 coins; blocked by %s)", game.Duke))
-	d.row++
-	d.buildString(d.row, 5, "[3] Coup (-7 coins; target loses influence)")
-	d.row++
-	d.buildString(d.row, 5, fmt.Sprintf("\033[37m[4] Assassinate (-3 coins; target loses influences; blocked by %s)", game.Contessa))
-	d.row++
-	d.buildString(d.row, 5, fmt.Sprintf("\033[32m[5] Exchange (Draw 2 cards, then return 2 cards)\033[0m"))
-	d.row++
-	d.buildString(d.row, 5, fmt.Sprintf("\033[36m[6] Steal (Take up to 2 coins from target; blocked by %s \033[36mor %s)", game.Ambassador, game.Captain))
-	d.row++
-	d.buildString(d.row, 5, "\033[35m[7] Tax (+3 coins)\033[0m")
-	d.row += 2
-	d.buildString(d.row, 1, "The time has come to act:")
-	d.row++
-}
-
-func (d *Display) drawTargetMenu() {
-	d.buildString(d.row, 5, "And you will act upon?")
-	d.row++
-	var counter int
-	for _, p := range d.validTargets {
-		counter++
-		d.buildString(d.row, 5, fmt.Sprintf("[%d] %s", counter, p.Name))
-		d.row++
+	if len(parts) == 0 {
+		return ""
 	}
+	return "Claims: " + strings.Join(parts, " ")
 }
 
-func (d *Display) drawChallengeMenu() { // Saved for later: pInfo []*Player) {
-	d.row++
-	d.buildString(d.row, 5, "[1] Challenge")
-	d.row++
-	d.buildString(d.row, 5, "[0] Pass")
-	d.row++
-}
-
-func (d *Display) drawBlockMenu() { // Saved for later: pInfo []*Player) {}
-	d.buildString(d.row, 5, "Will you block?")
-	d.row++
-	if d.State.Action != game.Steal {
-		d.buildString(d.row, 5, "[1] Block")
-		d.row++
-	} else {
-		d.buildString(d.row, 5, fmt.Sprintf("[1] Block with %s", game.Ambassador))
-		d.row++
-		d.buildString(d.row, 5, fmt.Sprintf("[2] Block with %s", game.Captain))
+// drawMenu shows the model's menu, or why there isn't one.
+func (d *Display) drawMenu() {
+	if d.model.Prompt != "" {
+		d.buildString(d.row, 5, d.model.Prompt)
 		d.row++
 	}
-	d.buildString(d.row, 5, "[0] Pass")
-	d.row++
-}
-
-func (d *Display) drawRevealMenu() {
-	d.buildString(d.row, 5, "Show the world the truth. Reveal a card:")
-	d.row++
-	for i, card := range d.activePlayers[0].CardsHeld {
-		d.buildString(d.row, 5, fmt.Sprintf("[%d] Reveal %s", i+1, card))
+	for i, o := range d.model.Options {
+		line := fmt.Sprintf("[%d] %s\033[0m", o.Key, o.Label)
+		if i == d.cursor {
+			line = highlightSelected(fmt.Sprintf("[%d] %s", o.Key, StripColor(o.Label)))
+		}
+		d.buildString(d.row, 5, line)
+		d.addHit(d.row, 5, line, o.Key)
 		d.row++
 	}
 }
 
-func (d *Display) drawLossMenu() {
-	d.buildString(d.row, 5, "Who has disappointed you? Choose a card to lose:")
-	d.row++
-	for i, card := range d.activePlayers[0].CardsHeld {
-		d.buildString(d.row, 5, fmt.Sprintf("[%d] Lose %s", i+1, card))
-		d.row++
+func (d *Display) drawHint() {
+	if !d.showHint {
+		return
 	}
-}
-
-func (d *Display) drawReturnTwo() {
-	d.buildString(d.row, 5, "Who do you no longer need? (Returned 0 of 2)")
 	d.row++
-	for i, card := range d.activePlayers[0].CardsHeld {
-		d.buildString(d.row, 5, fmt.Sprintf("[%d] Return %s", i+1, card))
+	if len(d.hint) == 0 {
+		d.buildString(d.row, 1, "Advisor: nothing for you to decide right now")
 		d.row++
+		return
 	}
-}
-
-func (d *Display) drawReturnOne() {
-	d.buildString(d.row, 5, "Who do you no longer need? (Returned 1 of 2)")
+	best := d.hint[0]
+	d.buildString(d.row, 1, fmt.Sprintf("Advisor suggests [%d] %s", best.Key, best.Label))
 	d.row++
-	for i, card := range d.activePlayers[0].CardsHeld {
-		d.buildString(d.row, 5, fmt.Sprintf("[%d] Return %s", i+1, card))
+	for _, o := range d.hint {
+		d.buildString(d.row, 5, fmt.Sprintf("[%d] %s  %3.0f%% to win", o.Key, o.Label, 100*o.WinRate))
 		d.row++
 	}
-	d.buildString(d.row, 5, fmt.Sprint("[0] Cancel"))
-	d.row++
 }
 
 func (d *Display) drawVictoryScreen() {
-	d.buildString(d.row, 0, fmt.Sprintf("The game is over, and %s is the victor!", d.victor))
+	d.buildString(d.row, 0, fmt.Sprintf("The game is over, and %s is the victor!", d.model.Victor))
+	if d.note != "" {
+		d.row += 2
+		d.buildString(d.row, 0, d.note)
+	}
 }
@@ -0,0 +1,186 @@
+package display
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	inp "kugo/input"
+)
+
+// cell is one character on screen, along with any marks such as accents
+// that sit on it, and the colour codes it is drawn with. A character two
+// columns wide fills the cell after it with one that is empty.
+type cell struct {
+	ch    string
+	style string
+}
+
+var blank = cell{ch: " "}
+
+// grid is a frame of the screen, a row of cells for each line. Rows only
+// reach as far as their last character, and grow as they are drawn on.
+type grid [][]cell
+
+// put draws str at row and col, counted from 1, taking the colour codes in it
+// as the style for the characters that follow them.
+func (g *grid) put(row, col int, str string) {
+	row, col = max(row, 1)-1, max(col, 1)-1
+	for len(*g) <= row {
+		*g = append(*g, nil)
+	}
+	line := (*g)[row]
+	var style string
+	for i := 0; i < len(str); {
+		if str[i] == '\033' {
+			end := strings.IndexFunc(str[i+1:], func(r rune) bool {
+				return r >= '@' && r <= '~' && r != '['
+			})
+			if end < 0 {
+				break
+			}
+			code := str[i : i+end+2]
+			i += end + 2
+			switch {
+			case !strings.HasSuffix(code, "m"):
+				// Only colours and styles mean anything inside a frame.
+			case code == "\033[0m" || code == "\033[m":
+				style = ""
+			default:
+				style += code
+			}
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(str[i:])
+		i += size
+		width := inp.RuneWidth(r)
+		if width == 0 {
+			// A mark goes on the character before it.
+			if prev := lead(line, col-1); prev >= 0 {
+				line[prev].ch += string(r)
+			}
+			continue
+		}
+		for len(line) < col+width {
+			line = append(line, blank)
+		}
+		// Don't leave half of a wide character behind.
+		if prev := lead(line, col); prev >= 0 && prev < col {
+			line[prev] = blank
+		}
+		if end := col + width; end < len(line) && line[end].ch == "" {
+			line[end] = blank
+		}
+		line[col] = cell{ch: string(r), style: style}
+		if width == 2 {
+			line[col+1] = cell{style: style}
+		}
+		col += width
+	}
+	(*g)[row] = line
+}
+
+// lead is where the character covering col in line starts, which is col
+// itself unless col is the second half of a wide one. It is -1 if col is off
+// the line.
+func lead(line []cell, col int) int {
+	if col < 0 || col >= len(line) {
+		return -1
+	}
+	if line[col].ch == "" && col > 0 {
+		return col - 1
+	}
+	return col
+}
+
+// at is the cell at row and col, counted from 0, which is blank if nothing
+// has been drawn there.
+func (g grid) at(row, col int) cell {
+	if row >= len(g) || col >= len(g[row]) {
+		return blank
+	}
+	return g[row][col]
+}
+
+// width is how far row reaches before it is only blanks.
+func (g grid) width(row int) int {
+	if row >= len(g) {
+		return 0
+	}
+	line := g[row]
+	n := len(line)
+	for n > 0 && line[n-1] == blank {
+		n--
+	}
+	return n
+}
+
+// wideChanged says whether the character at row and col is wide in to, and
+// its second half differs from what from has there.
+func wideChanged(from, to grid, row, col int) bool {
+	next := to.at(row, col+1)
+	return next.ch == "" && from.at(row, col+1) != next
+}
+
+// rewriteGap is how many unchanged cells diff will write out again to save
+// moving the cursor over them. It keeps the text of a changed line in one
+// piece, too.
+const rewriteGap = 8
+
+// diff writes out the escape codes that turn the screen showing from into
+// one showing to. A nil from means the screen is in an unknown state, so it
+// is cleared and to drawn in full.
+func diff(out *strings.Builder, from, to grid) {
+	if from == nil {
+		out.WriteString("\033[2J")
+	}
+	style := ""
+	cursorRow, cursorCol := -1, -1
+	write := func(row, col int) {
+		c := to.at(row, col)
+		if c.style != style {
+			out.WriteString("\033[0m" + c.style)
+			style = c.style
+		}
+		out.WriteString(c.ch)
+		cursorCol++
+		if to.at(row, col+1).ch == "" {
+			cursorCol++
+		}
+	}
+	for row := range max(len(from), len(to)) {
+		toWidth := to.width(row)
+		for col := range toWidth {
+			if to.at(row, col).ch == "" {
+				// The wide character before this drew it.
+				continue
+			}
+			if from != nil && from.at(row, col) == to.at(row, col) && !wideChanged(from, to, row, col) {
+				continue
+			}
+			if row == cursorRow && col >= cursorCol && col-cursorCol < rewriteGap {
+				for cursorCol < col {
+					write(row, cursorCol)
+				}
+			} else {
+				fmt.Fprintf(out, "\033[%d;%dH", row+1, col+1)
+				cursorRow, cursorCol = row, col
+			}
+			write(row, col)
+		}
+		if from.width(row) > toWidth {
+			if row != cursorRow || cursorCol != toWidth {
+				fmt.Fprintf(out, "\033[%d;%dH", row+1, toWidth+1)
+				cursorRow, cursorCol = row, toWidth
+			}
+			if style != "" {
+				out.WriteString("\033[0m")
+				style = ""
+			}
+			out.WriteString("\033[K")
+		}
+	}
+	if style != "" {
+		out.WriteString("\033[0m")
+	}
+}
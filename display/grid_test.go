@@ -0,0 +1,91 @@
+package display
+
+import (
+	"strings"
+	"testing"
+)
+
+func assertEqual[T comparable](t *testing.T, got, want T, desc string) {
+	t.Helper()
+	if got != want {
+		t.Errorf("%s: got %#v, want %#v", desc, got, want)
+	}
+}
+
+// drawn is a grid with each of lines put on a row of its own.
+func drawn(lines ...string) grid {
+	g := grid{}
+	for i, line := range lines {
+		g.put(i+1, 1, line)
+	}
+	return g
+}
+
+func TestGridPut(t *testing.T) {
+	tests := []struct {
+		name  string
+		puts  []string // put in turn at the column after the "|" in each
+		cells []cell
+	}{
+		{"plain", []string{"|ab"}, []cell{{ch: "a"}, {ch: "b"}}},
+		{"wide rune", []string{"|中a"}, []cell{{ch: "中"}, {}, {ch: "a"}}},
+		{"mark", []string{"|éx"}, []cell{{ch: "é"}, {ch: "x"}}},
+		{"past the end", []string{"  |a"}, []cell{blank, blank, {ch: "a"}}},
+		{"style", []string{"|\033[31mr\033[0mg"}, []cell{{ch: "r", style: "\033[31m"}, {ch: "g"}}},
+		{"styles add up", []string{"|\033[1m\033[31mr"}, []cell{{ch: "r", style: "\033[1m\033[31m"}}},
+		{"other codes", []string{"|\033[2Ka"}, []cell{{ch: "a"}}},
+		{"over the first half", []string{"|中", "|x"}, []cell{{ch: "x"}, blank}},
+		{"over the second half", []string{"|中", " |x"}, []cell{blank, {ch: "x"}}},
+		{"wide over wide", []string{"|中", " |中"}, []cell{blank, {ch: "中"}, {}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := grid{}
+			for _, p := range tt.puts {
+				col := strings.Index(p, "|")
+				g.put(1, col+1, p[col+1:])
+			}
+			assertEqual(t, len(g[0]), len(tt.cells), "cells")
+			for i, want := range tt.cells {
+				assertEqual(t, g.at(0, i), want, "cell")
+			}
+		})
+	}
+}
+
+func TestGridDiff(t *testing.T) {
+	menu := func(cursor int) grid {
+		lines := []string{"Log line", "[1] Income\033[0m", "[2] Tax\033[0m"}
+		lines[cursor+1] = highlightSelected(StripColor(lines[cursor+1]))
+		return drawn(lines...)
+	}
+	tests := []struct {
+		name     string
+		from, to grid
+		want     string
+	}{
+		{"unchanged", drawn("hello"), drawn("hello"), ""},
+		{"unknown screen", nil, drawn("hi", "", "yo"), "\033[2J\033[1;1Hhi\033[3;1Hyo"},
+		{"one cell", drawn("hello"), drawn("hallo"), "\033[1;2Ha"},
+		{"second row", drawn("one", "two"), drawn("one", "tw!"), "\033[2;3H!"},
+		{"small gap rewritten", drawn("abcdef"), drawn("xbcdey"), "\033[1;1Hxbcdey"},
+		{"large gap skipped", drawn("abcdefghijkl"), drawn("xbcdefghijky"), "\033[1;1Hx\033[1;12Hy"},
+		{"shorter line", drawn("hello"), drawn("he"), "\033[1;3H\033[K"},
+		{"longer line", drawn("he"), drawn("hello"), "\033[1;3Hllo"},
+		{"row gone", drawn("one", "two"), drawn("one"), "\033[2;1H\033[K"},
+		{"styled", drawn("ab"), drawn("\033[31mab"), "\033[1;1H\033[0m\033[31mab\033[0m"},
+		{"style dropped", drawn("\033[31mab"), drawn("ab"), "\033[1;1Hab"},
+		{"wide rune", drawn("ab"), drawn("中"), "\033[1;1H中"},
+		{"half of a wide rune", drawn("中"), drawn("a"), "\033[1;1Ha\033[K"},
+		{"mark", drawn("e"), drawn("é"), "\033[1;1Hé"},
+		{"cursor moved down", menu(0), menu(1), "\033[2;1H[1] Income\033[3;1H\033[0m\033[7m[2] Tax\033[0m"},
+		{"cursor moved up", menu(1), menu(0), "\033[2;1H\033[0m\033[7m[1] Income\033[3;1H\033[0m[2] Tax"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			diff(&out, tt.from, tt.to)
+			assertEqual(t, out.String(), tt.want, "diff")
+		})
+	}
+}
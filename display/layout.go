@@ -0,0 +1,183 @@
+package display
+
+import (
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+
+	inp "kugo/input"
+)
+
+// The table is laid out in one of two ways. Stacked puts every section under
+// the last, as on an ordinary terminal, trimming the action log and then the
+// card counts so the menu stays on screen. On a terminal at least
+// columnsWidth (156) wide the sections sit side by side instead, with the
+// board and the log under it on the left, and the hand and menu on the
+// right. At columnsWidth+logWidth (200) the log gets a third column of its
+// own between them.
+const (
+	boardWidth   = 72
+	menuWidth    = 84
+	logWidth     = 44
+	columnsWidth = boardWidth + menuWidth
+	chatWidth    = 40
+)
+
+// panel is the part of the screen being drawn in. Columns passed to
+// buildString are counted from its left edge, and anything past its width is
+// cut off. A width of zero doesn't cut anything.
+type panel struct {
+	left, width int
+}
+
+// Resize tells the Display how big its terminal is, which decides how the
+// table is laid out. It is redrawn to fit with the next frame. Until it is
+// called the Display assumes a terminal big enough for the stacked layout.
+func (d *Display) Resize(width, height int) {
+	d.publish(func(f *frame) { f.width, f.height = width, height })
+}
+
+// fitTerminal sizes the Display to the terminal on stdout, if there is one.
+func (d *Display) fitTerminal() {
+	if width, height, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+		d.Resize(width, height)
+	}
+}
+
+// drawTable lays out the game in whichever way suits the terminal.
+func (d *Display) drawTable() {
+	if d.frameWidth >= columnsWidth {
+		d.drawColumns()
+		return
+	}
+	counts := true
+	if d.frameHeight > 0 {
+		spare := d.frameHeight - d.stackedRows()
+		if spare < 1 && len(d.cardCounts) > 0 {
+			// The odds are the first thing to go on a short terminal.
+			spare += len(d.cardCounts) + 2
+			counts = false
+		}
+		d.logRows = max(spare, 1)
+	}
+	d.drawPlayers()
+	d.drawActionLog()
+	d.drawBotThoughts()
+	d.drawLocalHand()
+	if counts {
+		d.drawCardCounts()
+	}
+	d.drawMenu()
+	d.drawHint()
+}
+
+// drawColumns draws the board, log, and hand and menu side by side. The log
+// goes under the board if there isn't room for a column of its own.
+func (d *Display) drawColumns() {
+	top := d.row
+	log := d.frameWidth >= columnsWidth+logWidth
+	d.panel = panel{0, boardWidth}
+	d.drawPlayers()
+	d.drawCardCounts()
+	d.drawBotThoughts()
+	if log {
+		d.row = top
+		d.panel = panel{boardWidth, d.frameWidth - boardWidth - menuWidth}
+	}
+	if d.frameHeight > 0 {
+		d.logRows = max(d.frameHeight-d.row+1-d.chatRows(), 1)
+	}
+	d.chatBelow = true
+	d.drawActionLog()
+	d.chatBelow = false
+	d.row = top
+	d.panel = panel{d.frameWidth - menuWidth, menuWidth}
+	d.drawLocalHand()
+	d.drawMenu()
+	d.drawHint()
+	d.panel = panel{width: d.frameWidth}
+}
+
+// stackedRows counts the rows the stacked layout needs for everything but the
+// action log, the header included, so the log can be given whatever is left.
+func (d *Display) stackedRows() int {
+	rows := d.row - 1 + len(d.model.Players) + 2
+	if d.thoughts != nil {
+		rows += len(d.thoughts) + 2
+	}
+	for _, row := range d.model.Players {
+		if row.Local {
+			rows += 2
+			break
+		}
+	}
+	if len(d.cardCounts) > 0 {
+		rows += len(d.cardCounts) + 2
+	}
+	if d.model.Prompt != "" {
+		rows++
+	}
+	rows += len(d.model.Options)
+	if d.showHint {
+		rows += 2 + len(d.hint)
+	}
+	if !d.chatBeside() {
+		rows += d.chatRows()
+	}
+	return rows
+}
+
+// chatBeside says whether the chat panel fits beside the action log.
+func (d *Display) chatBeside() bool {
+	return !d.chatBelow && (d.frameWidth == 0 || d.frameWidth >= chatColumn+chatWidth)
+}
+
+// chatRows is how many rows the chat panel takes up.
+func (d *Display) chatRows() int {
+	if !d.showChat {
+		return 0
+	}
+	rows := len(d.chat) + 2
+	if d.chatTyping {
+		rows++
+	}
+	return rows + len(d.emotes)
+}
+
+// clip cuts s down to at most width columns on screen, leaving colour
+// codes alone so they don't bleed into the next line.
+func clip(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	var b strings.Builder
+	var shown int
+	var styled bool
+	for i := 0; i < len(s); {
+		if s[i] == '\033' {
+			end := strings.IndexFunc(s[i+1:], func(r rune) bool {
+				return r >= '@' && r <= '~' && r != '['
+			})
+			if end < 0 {
+				break
+			}
+			b.WriteString(s[i : i+end+2])
+			i += end + 2
+			styled = true
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if shown+inp.RuneWidth(r) > width {
+			if styled {
+				b.WriteString("\033[0m")
+			}
+			return b.String()
+		}
+		b.WriteString(s[i : i+size])
+		i += size
+		shown += inp.RuneWidth(r)
+	}
+	return b.String()
+}
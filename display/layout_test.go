@@ -0,0 +1,124 @@
+package display
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"kugo/game"
+)
+
+// testDisplay draws a game on a terminal width by height, after everyone has
+// taken income once so there is something in the log and it is Alice's turn
+// again, and then moves have been made.
+func testDisplay(t *testing.T, width, height int, moves ...*game.InputData) *Display {
+	t.Helper()
+	var players []*game.Player
+	for i, name := range []string{"Alice", "Bob", "Charlie"} {
+		p, err := game.NewPlayer(name, i, i == 0, i == 0)
+		if err != nil {
+			t.Fatalf("NewPlayer: %v", err)
+		}
+		players = append(players, p)
+	}
+	c := game.NewController(players)
+	c.ShuffleAndDeal()
+	for i := range players {
+		c.UpdateGame(game.NewInputData(1, i))
+		c.UpdateGame(game.NewInputData(0, i))
+	}
+	for _, move := range moves {
+		c.UpdateGame(move)
+	}
+	d := NewDisplayTo(io.Discard, nil)
+	d.Resize(width, height)
+	d.UpdateDisplay(c.GetDisplayData())
+	d.draw(d.current())
+	return d
+}
+
+// find is where text first shows on screen, counted from 1, or 0 and 0 if
+// it doesn't. Only text one column to a character can be found.
+func (g grid) find(text string) (row, col int) {
+	for r := range g {
+		if i := strings.Index(g.text(r+1), text); i >= 0 {
+			return r + 1, i + 1
+		}
+	}
+	return 0, 0
+}
+
+// text is what is drawn on row, counted from 1.
+func (g grid) text(row int) string {
+	var b strings.Builder
+	for _, c := range g[row-1] {
+		b.WriteString(c.ch)
+	}
+	return b.String()
+}
+
+func TestLayout(t *testing.T) {
+	type place struct{ row, col int }
+	tests := []struct {
+		name          string
+		width, height int
+		header        place
+		players       place
+		log           place
+		hand          place
+		menu          place
+	}{
+		// Stacked, each section under the last.
+		{"unknown size", 0, 0, place{1, 16}, place{3, 5}, place{7, 1}, place{14, 5}, place{24, 5}},
+		{"narrow", 100, 40, place{1, 16}, place{3, 5}, place{7, 1}, place{14, 5}, place{24, 5}},
+		// The board on the left with the log under it, the hand and menu on
+		// the right.
+		{"columns", columnsWidth, 40, place{1, 72}, place{3, 5}, place{14, 1}, place{3, 77}, place{6, 77}},
+		// The log between the board and the menu.
+		{"three columns", columnsWidth + logWidth, 40, place{1, 94}, place{3, 5}, place{3, 73}, place{3, 121}, place{6, 121}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := testDisplay(t, tt.width, tt.height)
+			for _, section := range []struct {
+				text string
+				want place
+			}{
+				{"=== KUGO ===", tt.header},
+				{"Alice", tt.players},
+				{"Alice has selected Income", tt.log},
+				{"Your hand", tt.hand},
+				{"[1] ", tt.menu},
+			} {
+				row, col := d.front.find(section.text)
+				assertEqual(t, place{row, col}, section.want, section.text)
+			}
+		})
+	}
+}
+
+func TestLayoutTrimsLog(t *testing.T) {
+	tests := []struct {
+		name   string
+		height int
+		log    bool
+		counts bool
+	}{
+		{"room for everything", 30, true, true},
+		{"oldest log lines go", 26, false, true},
+		{"then the odds", 18, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := testDisplay(t, 100, tt.height)
+			row, _ := d.front.find("[7] ")
+			assertEqual(t, row > 0 && row <= tt.height, true, "whole menu on screen")
+			row, _ = d.front.find("Charlie gains 1 coin")
+			assertEqual(t, row > 0, true, "newest log line shown")
+			row, _ = d.front.find("Alice has selected Income")
+			assertEqual(t, row > 0, tt.log, "oldest log line shown")
+			row, _ = d.front.find("Unseen")
+			assertEqual(t, row > 0, tt.counts, "odds shown")
+		})
+	}
+}
@@ -2,6 +2,8 @@ package display
 
 import(
 	"fmt"
+
+	inp "kugo/input"
 )
 
 const HelpText = `
@@ -59,24 +61,42 @@ func highlightSelected(str string) string {
 	return fmt.Sprintf("\033[7m%s\033[0m", str)
 }
 
-func highlight[T any](value T) string {
-	return fmt.Sprintf("\033[7m%v\033[0m", value)
+// drawMenuHelp lists the keys that work everywhere, under the main menu.
+func (d *Display) drawMenuHelp() {
+	d.row++
+	d.buildString(d.row, 5, "press 'q' at any time to quit")
+	d.row++
+	d.buildString(d.row, 5, "press 't' in game to toggle bot thoughts")
+	d.row++
+	d.buildString(d.row, 5, "press '?' in game to ask the advisor")
 }
 
-func (d *Display) DrawMainMenu() {
-	d.buildString(d.row, 3, "select number of players by number keys")
-	d.row += 2
-	d.buildString(d.row, 12, "# Players: ")
-	for i := range 4 {
-		if i == d.Selection {
-			d.buildString(d.row, 23 + i*2, fmt.Sprintf("%s", highlight(i+3)))
-			continue
-		}
-		d.buildString(d.row, 23 + i*2, fmt.Sprintf("%d", i+3))
+// Navigate moves the cursor over the menu on screen for the arrow keys and for j
+// and k, reporting that the key did nothing else. Enter is turned into the
+// number key of the option under the cursor. Every other key, and every key
+// while there is no menu on screen, is returned as it is.
+func (d *Display) Navigate(key rune) (rune, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	f := &d.latest
+	if f.model == nil || f.curtain != "" || len(f.model.Options) == 0 {
+		return key, false
+	}
+	n := len(f.model.Options)
+	switch key {
+	case inp.KeyUp, 'k':
+		f.cursor = (f.cursor + n - 1) % n
+	case inp.KeyDown, 'j':
+		f.cursor = (f.cursor + 1) % n
+	case inp.KeyHome:
+		f.cursor = 0
+	case inp.KeyEnd:
+		f.cursor = n - 1
+	case '\r', '\n':
+		return rune('0' + f.model.Options[min(f.cursor, n-1)].Key), false
+	default:
+		return key, false
 	}
-	d.row += 4
-	d.buildString(d.row, 12, "press Enter to begin")
-	d.row += 2
-	d.buildString(d.row, 8, "press 'q' at any time to quit")
+	d.send()
+	return 0, true
 }
-
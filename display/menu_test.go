@@ -0,0 +1,107 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	inp "kugo/input"
+)
+
+func TestNavigate(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []rune
+		want    rune
+		handled bool
+		cursor  int
+	}{
+		{"down", []rune{inp.KeyDown}, 0, true, 1},
+		{"j", []rune{'j', 'j'}, 0, true, 2},
+		{"up wraps", []rune{inp.KeyUp}, 0, true, 6},
+		{"k", []rune{'k', 'k'}, 0, true, 5},
+		{"down wraps", []rune{inp.KeyEnd, inp.KeyDown}, 0, true, 0},
+		{"end", []rune{inp.KeyEnd}, 0, true, 6},
+		{"home", []rune{inp.KeyEnd, inp.KeyHome}, 0, true, 0},
+		{"enter", []rune{'\r'}, '1', false, 0},
+		{"enter after moving", []rune{inp.KeyDown, inp.KeyDown, '\n'}, '3', false, 2},
+		{"number", []rune{inp.KeyDown, '7'}, '7', false, 1},
+		{"other key", []rune{'q'}, 'q', false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := testDisplay(t, 100, 40)
+			var got rune
+			var handled bool
+			for _, key := range tt.keys {
+				got, handled = d.Navigate(key)
+			}
+			assertEqual(t, got, tt.want, "key")
+			assertEqual(t, handled, tt.handled, "handled")
+			assertEqual(t, d.current().cursor, tt.cursor, "cursor")
+		})
+	}
+}
+
+func TestNavigateWithoutMenu(t *testing.T) {
+	tests := []struct {
+		name string
+		d    func(t *testing.T) *Display
+	}{
+		{"no game", func(*testing.T) *Display { return NewDisplayTo(nil, nil) }},
+		{"curtain", func(t *testing.T) *Display {
+			d := testDisplay(t, 100, 40)
+			d.DrawCurtain("Pass to Alice - press Enter")
+			return d
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := tt.d(t)
+			for _, key := range []rune{inp.KeyDown, 'j', '\r'} {
+				got, handled := d.Navigate(key)
+				assertEqual(t, got, key, "key")
+				assertEqual(t, handled, false, "handled")
+			}
+		})
+	}
+}
+
+func TestNavigateRedrawsOnlyTheMenu(t *testing.T) {
+	d := testDisplay(t, 100, 40)
+	var out strings.Builder
+	d.out = &out
+	d.Navigate(inp.KeyDown)
+	d.draw(d.current())
+
+	moves := regexp.MustCompile(`\033\[\d+;\d+H`).FindAllString(out.String(), -1)
+	assertEqual(t, strings.Join(moves, ""), "\033[24;5H\033[25;5H", "cursor moves")
+	assertEqual(t, strings.Contains(out.String(), "\033[7m[2] Foreign Aid"), true, "second option highlighted")
+}
+
+func TestMenuScreen(t *testing.T) {
+	options := func(players int) []MenuOption {
+		return []MenuOption{{Key: 1, Label: "Play"}, {Key: 2, Label: fmt.Sprintf("Players: %d", players)}}
+	}
+	d := NewDisplayTo(io.Discard, nil)
+	d.DrawMenuScreen("Pick one:", options(3))
+	key, moved := d.Navigate(inp.KeyDown)
+	assertEqual(t, moved, true, "moved")
+	key, _ = d.Navigate('\r')
+	assertEqual(t, key, '2', "Enter on the second option")
+
+	// Changing an option's label leaves the cursor on it.
+	d.DrawMenuScreen("Pick one:", options(4))
+	assertEqual(t, d.current().cursor, 1, "cursor after a label changed")
+	row, col := d.front.find("[2] Players: 4")
+	assertEqual(t, d.front.at(row-1, col-1).style, "\033[7m", "highlighted option")
+	got, ok := d.Click(row, col)
+	assertEqual(t, got, '2', "clicked option")
+	assertEqual(t, ok, true, "clicked")
+
+	// A different menu starts at the top.
+	d.DrawMenuScreen("Pick one:", options(4)[:1])
+	assertEqual(t, d.current().cursor, 0, "cursor on a new menu")
+}
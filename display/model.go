@@ -0,0 +1,203 @@
+package display
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"kugo/game"
+)
+
+// Model is what a screen of the game shows: the player rows, the log, the
+// local hand and the menu, worked out once for any interface to lay out. The
+// terminal Display draws it, and the web client is sent it as JSON.
+//
+// Text is written as the game writes it, which may carry colour codes for the
+// terminal. Plain returns a copy without them.
+type Model struct {
+	Players []Row        `json:"players"`
+	Log     []string     `json:"log"`
+	Hand    []string     `json:"hand"`
+	Prompt  string       `json:"prompt"`
+	Options []MenuOption `json:"options"`
+	Victor  string       `json:"victor,omitempty"`
+	Over    bool         `json:"over"`
+}
+
+// Row is one player's line in the table. Hand shows their lost cards, and
+// hides the rest unless the view reveals them. Claims lists the roles they
+// have claimed, and is empty for the local player.
+type Row struct {
+	Name    string `json:"name"`
+	Coins   int    `json:"coins"`
+	Alive   bool   `json:"alive"`
+	Current bool   `json:"current"`
+	Local   bool   `json:"local"`
+	Hand    string `json:"hand"`
+	Claims  string `json:"claims"`
+}
+
+// MenuOption is a choice on the menu and the key that makes it.
+type MenuOption struct {
+	Key   int    `json:"key"`
+	Label string `json:"label"`
+}
+
+// NewModel works out the model for a screen of the game. The menu is only
+// filled in when one of the local players has a choice to make; otherwise the
+// prompt says why not.
+func NewModel(info *game.DisplayData) *Model {
+	m := Model{Log: []string{}, Hand: []string{}, Options: []MenuOption{}}
+	profiles := game.BuildProfiles(len(info.AllPlayers), info.History)
+	for _, p := range info.AllPlayers {
+		row := Row{
+			Name:    p.Name,
+			Coins:   p.Coins,
+			Alive:   p.IsAlive(),
+			Current: info.Current != nil && p.Index == info.Current.Index,
+			Local:   p.IsLocal,
+			Hand:    getHandString(p, info.Revealed),
+		}
+		if !p.IsLocal {
+			row.Claims = getClaimString(profiles[p.Index])
+		}
+		m.Players = append(m.Players, row)
+		if p.IsLocal && len(m.Hand) == 0 {
+			for _, card := range p.CardsHeld {
+				m.Hand = append(m.Hand, card.String())
+			}
+		}
+	}
+	if info.ActionLog != nil {
+		m.Log = append(m.Log, info.ActionLog.Items...)
+	}
+	if info.State.Phase == game.EndGame {
+		m.Over = true
+		for _, p := range info.AllPlayers {
+			if p.IsAlive() {
+				m.Victor = p.Name
+			}
+		}
+		return &m
+	}
+	m.Prompt, m.Options = menuFor(info)
+	return &m
+}
+
+// menuFor is the prompt and options for the phase the game is in.
+func menuFor(info *game.DisplayData) (string, []MenuOption) {
+	if info.Spectating {
+		return "Spectating...", nil
+	}
+	var local *game.Player
+	for _, p := range info.ActivePlayers {
+		if p.IsLocal {
+			local = p
+			break
+		}
+	}
+	if local == nil {
+		return "Biding your time...", nil
+	}
+	var options []MenuOption
+	add := func(key int, label string) {
+		options = append(options, MenuOption{Key: key, Label: label})
+	}
+	cards := func(verb string) {
+		for i, card := range local.CardsHeld {
+			add(i+1, fmt.Sprintf("%s %s", verb, card))
+		}
+	}
+	switch info.State.Phase {
+	case game.SelectAction:
+		add(1, "Income (+1 coin)")
+		add(2, fmt.Sprintf("Foreign Aid (+2 coins; blocked by %s)", game.Duke))
+		add(3, "Coup (-7 coins; target loses influence)")
+		add(4, fmt.Sprintf("\033[37mAssassinate (-3 coins; target loses influences; blocked by %s)", game.Contessa))
+		add(5, "\033[32mExchange (Draw 2 cards, then return 2 cards)\033[0m")
+		add(6, fmt.Sprintf("\033[36mSteal (Take up to 2 coins from target; blocked by %s \033[36mor %s)", game.Ambassador, game.Captain))
+		add(7, "\033[35mTax (+3 coins)\033[0m")
+		return "The time has come to act:", options
+	case game.SelectTarget:
+		for i, p := range info.ValidTargets {
+			add(i+1, p.Name)
+		}
+		return "And you will act upon?", options
+	case game.MakeChallenge, game.ChallengeBlock:
+		add(1, "Challenge")
+		add(0, "Pass")
+		return "Will you challenge?", options
+	case game.MakeBlock:
+		if info.State.Action != game.Steal {
+			add(1, "Block")
+		} else {
+			add(1, fmt.Sprintf("Block with %s", game.Ambassador))
+			add(2, fmt.Sprintf("Block with %s", game.Captain))
+		}
+		add(0, "Pass")
+		return "Will you block?", options
+	case game.ChallengeReveal, game.BlockReveal:
+		cards("Reveal")
+		return "Show the world the truth. Reveal a card:", options
+	case game.ChallengeLoss, game.BlockLoss:
+		cards("Lose")
+		return "Who has disappointed you? Choose a card to lose:", options
+	case game.ResolveAction:
+		if info.State.Action != game.Assassinate && info.State.Action != game.Coup {
+			return "", nil
+		}
+		cards("Lose")
+		return "Who has disappointed you? Choose a card to lose:", options
+	case game.ExchangeMiddle:
+		cards("Return")
+		return "Who do you no longer need? (Returned 0 of 2)", options
+	case game.ExchangeFinal:
+		cards("Return")
+		add(0, "Cancel")
+		return "Who do you no longer need? (Returned 1 of 2)", options
+	default:
+		panic("Unreachable code! (menuFor)")
+	}
+}
+
+// colorCode matches the terminal's colour and style codes.
+var colorCode = regexp.MustCompile("\033\\[[0-9;]*m")
+
+// StripColor removes terminal colour codes from s.
+func StripColor(s string) string {
+	return colorCode.ReplaceAllString(s, "")
+}
+
+// Plain returns a copy of the model with the colour codes taken out of its
+// text, for interfaces that aren't terminals.
+func (m *Model) Plain() *Model {
+	plain := *m
+	strip := func(lines []string) []string {
+		out := make([]string, len(lines))
+		for i, line := range lines {
+			out[i] = StripColor(line)
+		}
+		return out
+	}
+	plain.Log = strip(m.Log)
+	plain.Hand = strip(m.Hand)
+	plain.Players = make([]Row, len(m.Players))
+	for i, row := range m.Players {
+		row.Hand = StripColor(row.Hand)
+		row.Claims = StripColor(row.Claims)
+		plain.Players[i] = row
+	}
+	plain.Prompt = StripColor(m.Prompt)
+	plain.Options = make([]MenuOption, len(m.Options))
+	for i, o := range m.Options {
+		o.Label = StripColor(o.Label)
+		plain.Options[i] = o
+	}
+	plain.Victor = StripColor(m.Victor)
+	return &plain
+}
+
+// joinCards lays out a hand of cards the way the table shows them.
+func joinCards(cards []string) string {
+	return "[" + strings.Join(cards, " | ") + "]"
+}
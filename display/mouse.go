@@ -0,0 +1,91 @@
+package display
+
+import (
+	"context"
+	"fmt"
+	"kugo/game"
+	inp "kugo/input"
+)
+
+// Mouse reporting, in xterm's SGR encoding. A terminal sends clicks once
+// MouseOn has been written to it, until MouseOff is.
+const (
+	MouseOn  = "\033[?1000h\033[?1006h"
+	MouseOff = "\033[?1006l\033[?1000l"
+)
+
+// hit is a stretch of a row on screen that makes a move when clicked. Rows
+// and columns are counted from 1, and right is just past the end.
+type hit struct {
+	row, left, right int
+	key              int
+}
+
+// reportMouse has the terminal report clicks while the game is being drawn,
+// which stops when ctx is done. It is only on while there is a table to
+// click, as a terminal reporting clicks won't let the mouse select text.
+func (d *Display) reportMouse(ctx context.Context) {
+	d.drawMu.Lock()
+	fmt.Fprint(d.out, MouseOn)
+	d.drawMu.Unlock()
+	context.AfterFunc(ctx, func() {
+		d.drawMu.Lock()
+		defer d.drawMu.Unlock()
+		fmt.Fprint(d.out, MouseOff)
+	})
+}
+
+// addHit makes str, drawn at row and col as buildString would, play key when
+// clicked.
+func (d *Display) addHit(row, col int, str string, key int) {
+	if d.frameHeight > 0 && row > d.frameHeight {
+		return
+	}
+	width := inp.StringWidth(StripColor(str))
+	if d.panel.width > 0 {
+		width = min(width, d.panel.width-max(col-1, 0))
+	}
+	if width <= 0 {
+		return
+	}
+	left := max(col, 1) + d.panel.left
+	d.hits = append(d.hits, hit{row: row, left: left, right: left + width, key: key})
+}
+
+// addCardHits makes each card in the local hand, drawn by joinCards from
+// col, play the option for it when a card has to be picked.
+func (d *Display) addCardHits(row, col int) {
+	switch d.state.Phase {
+	case game.ChallengeReveal, game.BlockReveal, game.ChallengeLoss, game.BlockLoss,
+		game.ResolveAction, game.ExchangeMiddle, game.ExchangeFinal:
+	default:
+		return
+	}
+	keys := make(map[int]bool)
+	for _, o := range d.model.Options {
+		keys[o.Key] = true
+	}
+	for i, card := range d.model.Hand {
+		width := inp.StringWidth(StripColor(card))
+		if keys[i+1] {
+			d.addHit(row, col, card, i+1)
+		}
+		col += width + len(" | ")
+	}
+}
+
+// Click finds the move drawn at row and col on the screen, and the number key
+// that makes it.
+func (d *Display) Click(row, col int) (rune, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.latest.curtain != "" {
+		return 0, false
+	}
+	for _, h := range d.clickable {
+		if h.row == row && col >= h.left && col < h.right {
+			return rune('0' + h.key), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,75 @@
+package display
+
+import (
+	"strings"
+	"testing"
+
+	"kugo/game"
+)
+
+func TestClick(t *testing.T) {
+	steal := []*game.InputData{game.NewInputData(6, 0)}
+	exchange := []*game.InputData{game.NewInputData(5, 0), game.NewInputData(0, 1), game.NewInputData(0, 2)}
+	tests := []struct {
+		name     string
+		width    int
+		moves    []*game.InputData
+		row, col int
+		want     rune
+		ok       bool
+	}{
+		// "[1] Income (+1 coin)" is drawn from column 5 on row 24.
+		{"menu option", 100, nil, 24, 5, '1', true},
+		{"end of an option", 100, nil, 24, 24, '1', true},
+		{"past an option", 100, nil, 24, 25, 0, false},
+		{"before an option", 100, nil, 24, 4, 0, false},
+		{"last option", 100, nil, 30, 5, '7', true},
+		{"log", 100, nil, 7, 1, 0, false},
+		{"player not a target", 100, nil, 4, 5, 0, false},
+		{"hand when not picking a card", 100, nil, 14, 17, 0, false},
+		{"menu in columns", columnsWidth, nil, 6, 77, '1', true},
+		{"left of the menu in columns", columnsWidth, nil, 6, 5, 0, false},
+		{"menu in three columns", columnsWidth + logWidth, nil, 12, 121, '7', true},
+		{"target", 100, steal, 4, 1, '1', true},
+		{"other target", 100, steal, 5, 30, '2', true},
+		{"yourself", 100, steal, 3, 5, 0, false},
+		// "Your hand: [" leaves the first card at column 17.
+		{"card", 100, exchange, 17, 17, '1', true},
+		{"bracket", 100, exchange, 17, 16, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := testDisplay(t, tt.width, 40, tt.moves...)
+			got, ok := d.Click(tt.row, tt.col)
+			assertEqual(t, got, tt.want, "key")
+			assertEqual(t, ok, tt.ok, "clicked")
+		})
+	}
+}
+
+func TestClickCards(t *testing.T) {
+	exchange := []*game.InputData{game.NewInputData(5, 0), game.NewInputData(0, 1), game.NewInputData(0, 2)}
+	d := testDisplay(t, 100, 40, exchange...)
+	// The cards are dealt at random, so find each where it was drawn.
+	row, col := d.front.find("Your hand: [")
+	col += len("Your hand: [")
+	cards := strings.Split(strings.TrimSuffix(d.front.text(row)[col-1:], "]"), " | ")
+	assertEqual(t, len(cards), 4, "cards in hand")
+	for i, card := range cards {
+		got, ok := d.Click(row, col)
+		assertEqual(t, got, rune('1'+i), card)
+		assertEqual(t, ok, true, card)
+		got, ok = d.Click(row, col+len(card)-1)
+		assertEqual(t, got, rune('1'+i), card+" at its end")
+		assertEqual(t, ok, true, card+" at its end")
+		col += len(card) + len(" | ")
+	}
+}
+
+func TestClickBehindCurtain(t *testing.T) {
+	d := testDisplay(t, 100, 40)
+	d.DrawCurtain("Pass to Alice - press Enter")
+	if _, ok := d.Click(24, 5); ok {
+		t.Errorf("clicked a move behind the curtain")
+	}
+}
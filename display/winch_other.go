@@ -0,0 +1,9 @@
+//go:build !unix
+
+package display
+
+import "context"
+
+// followTerminal does nothing where there is no SIGWINCH to say the terminal
+// has changed size. The Display keeps the size it started with.
+func (d *Display) followTerminal(ctx context.Context) {}
@@ -0,0 +1,28 @@
+//go:build unix
+
+package display
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// followTerminal resizes the Display whenever the terminal on stdout changes
+// size, until ctx is done.
+func (d *Display) followTerminal(ctx context.Context) {
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	go func() {
+		defer signal.Stop(winch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-winch:
+				d.fitTerminal()
+			}
+		}
+	}()
+}
@@ -0,0 +1,282 @@
+package game
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+)
+
+// maxRolloutSteps caps how many inputs a single simulated game may take, in
+// case the rollout policy ends up going round in circles.
+const maxRolloutSteps = 500
+
+// Option is one legal move for a seat. Key is the number the player presses
+// to make it, and Input is what the controller receives as a result. WinRate
+// is only filled in by Advise.
+type Option struct {
+	Key     int
+	Input   InputData
+	Label   string
+	WinRate float64
+}
+
+// LegalOptions lists every move open to the view's seat in the current phase,
+// in the order they appear on the menu. It is empty if the seat has nothing to
+// decide. Cancelling a target or an exchange is never offered, as it only
+// takes the player back a step.
+func LegalOptions(v *View) []Option {
+	if !v.IsActive() {
+		return nil
+	}
+	var options []Option
+	add := func(key, sel int, label string) {
+		options = append(options, Option{Key: key, Input: *NewInputData(sel, v.Seat), Label: label})
+	}
+	me := v.Players[v.Seat]
+	switch v.Phase {
+	case SelectAction:
+		for a := Income; a <= Tax; a++ {
+			if me.Coins >= 10 && a != Coup {
+				continue
+			}
+			if (a == Coup && me.Coins < 7) || (a == Assassinate && me.Coins < 3) {
+				continue
+			}
+			add(int(a), int(a), a.String())
+		}
+	case SelectTarget:
+		var key int
+		for _, p := range v.Players {
+			if p.Index == v.Current || len(p.CardsLost) == 2 {
+				continue
+			}
+			key++
+			add(key, key, fmt.Sprintf("%s %s", v.Action, p.Name))
+		}
+	case MakeChallenge, ChallengeBlock:
+		add(1, 1, "Challenge")
+		add(0, 0, "Pass")
+	case MakeBlock:
+		for i, card := range v.Action.Blockers() {
+			add(i+1, i+1, fmt.Sprintf("Block with %s", card))
+		}
+		add(0, 0, "Pass")
+	case ChallengeReveal, BlockReveal:
+		for i, card := range me.CardsHeld {
+			add(i+1, i, fmt.Sprintf("Reveal %s", card))
+		}
+	case ChallengeLoss, BlockLoss:
+		for i, card := range me.CardsHeld {
+			add(i+1, i, fmt.Sprintf("Lose %s", card))
+		}
+	case ResolveAction:
+		if v.Action != Coup && v.Action != Assassinate {
+			return nil
+		}
+		for i, card := range me.CardsHeld {
+			add(i+1, i, fmt.Sprintf("Lose %s", card))
+		}
+	case ExchangeMiddle:
+		for i, card := range me.CardsHeld {
+			add(i+1, i, fmt.Sprintf("Return %s", card))
+		}
+	case ExchangeFinal:
+		for i, card := range me.CardsHeld {
+			add(i+1, i+1, fmt.Sprintf("Return %s", card))
+		}
+	}
+	return options
+}
+
+// Advise estimates how often the view's seat goes on to win after each of its
+// legal options, by playing the rest of the game out many times with the
+// hidden cards dealt at random. Options are returned best first.
+//
+// Only the redacted view is used, so the advice can't peek at anyone's hand.
+func Advise(v *View, rollouts int, rng *rand.Rand) []Option {
+	options := LegalOptions(v)
+	for i := range options {
+		var wins int
+		for range rollouts {
+			c := v.determinize(rng)
+			input := options[i].Input
+			if c.playOut(v.Seat, &input, rng) {
+				wins++
+			}
+		}
+		options[i].WinRate = float64(wins) / float64(rollouts)
+	}
+	slices.SortStableFunc(options, func(a, b Option) int {
+		return cmp.Compare(b.WinRate, a.WinRate)
+	})
+	return options
+}
+
+// determinize deals the unseen cards out at random to fill everyone else's
+// hand, giving a controller for one possible game consistent with the view.
+func (v *View) determinize(rng *rand.Rand) *Controller {
+	unseen := v.Unseen()
+	rng.Shuffle(len(unseen), func(i, j int) {
+		unseen[i], unseen[j] = unseen[j], unseen[i]
+	})
+	var players []*Player
+	for _, pv := range v.Players {
+		p := Player{
+			Name:      pv.Name,
+			Index:     pv.Index,
+			Coins:     pv.Coins,
+			CardsLost: slices.Clone(pv.CardsLost),
+			IsHuman:   pv.IsHuman,
+		}
+		if pv.Index == v.Seat {
+			p.CardsHeld = slices.Clone(pv.CardsHeld)
+		} else {
+			n := min(pv.HandSize, len(unseen))
+			p.CardsHeld = slices.Clone(unseen[:n])
+			unseen = unseen[n:]
+		}
+		players = append(players, &p)
+	}
+	playerAt := func(idx int) *Player {
+		if idx < 0 {
+			return nil
+		}
+		return players[idx]
+	}
+	c := NewController(players)
+	c.rng = rand.New(rand.NewPCG(rng.Uint64(), rng.Uint64()))
+	c.deck = unseen
+	c.State = v.State
+	c.current = playerAt(v.Current)
+	c.target = playerAt(v.Target)
+	c.blocker = playerAt(v.Blocker)
+	c.challenger = playerAt(v.Challenger)
+	c.blockType = v.BlockType
+	c.returnedCards = slices.Clone(v.Returned)
+	c.simulated = true
+	c.setActivePlayers()
+	return c
+}
+
+// playOut applies the seat's chosen input and then plays the game to the end
+// using policyInput for everyone. It reports whether the seat won.
+func (c *Controller) playOut(seat int, first *InputData, rng *rand.Rand) (won bool) {
+	// A world dealt at random can occasionally wander somewhere the handlers
+	// don't expect. Count that as a loss rather than taking the game down.
+	defer func() {
+		if recover() != nil {
+			won = false
+		}
+	}()
+	// Passing only means the seat stays quiet; the others still get their say.
+	if first.Selection == 0 && len(c.activePlayers) > 1 {
+		first = c.policyInput(rng, seat)
+	}
+	c.UpdateGame(first)
+	for range maxRolloutSteps {
+		if c.Phase == EndGame {
+			break
+		}
+		c.UpdateGame(c.policyInput(rng, -1))
+	}
+	for _, p := range c.AllPlayers {
+		if p.IsAlive() != (p.Index == seat) {
+			return false
+		}
+	}
+	return true
+}
+
+// policyInput is the strategy every player follows during a rollout: play the
+// cards you hold, reveal honestly, and only challenge claims that look
+// unlikely. The player at index exclude never responds to a challenge or
+// block window.
+func (c *Controller) policyInput(rng *rand.Rand, exclude int) *InputData {
+	switch c.Phase {
+	case SelectAction:
+		return NewInputData(int(c.policyAction(rng)), c.current.Index)
+	case SelectTarget:
+		return NewInputData(rng.IntN(len(c.getValidTargets()))+1, c.current.Index)
+	case MakeChallenge, ChallengeBlock:
+		card := c.Action.Card()
+		if c.Phase == ChallengeBlock {
+			card = c.blockType
+		}
+		for _, p := range c.activePlayers {
+			if p.Index != exclude && c.policyChallenges(p, card, rng) {
+				return NewInputData(1, p.Index)
+			}
+		}
+	case MakeBlock:
+		for _, p := range c.activePlayers {
+			if p.Index == exclude {
+				continue
+			}
+			for i, card := range c.Action.Blockers() {
+				if slices.Contains(p.CardsHeld, card) {
+					return NewInputData(i+1, p.Index)
+				}
+			}
+			if rng.IntN(100) < 10 {
+				return NewInputData(1, p.Index)
+			}
+		}
+	case ChallengeReveal, BlockReveal:
+		p := c.activePlayers[0]
+		claimed := c.Action.Card()
+		if c.Phase == BlockReveal {
+			claimed = c.blockType
+		}
+		return NewInputData(max(0, slices.Index(p.CardsHeld, claimed)), p.Index)
+	case ChallengeLoss, BlockLoss:
+		p := c.activePlayers[0]
+		return NewInputData(rng.IntN(len(p.CardsHeld)), p.Index)
+	case ResolveAction:
+		if (c.Action == Coup || c.Action == Assassinate) && c.target.IsAlive() {
+			return NewInputData(rng.IntN(len(c.target.CardsHeld)), c.target.Index)
+		}
+	case ExchangeMiddle:
+		return NewInputData(rng.IntN(len(c.current.CardsHeld)), c.current.Index)
+	case ExchangeFinal:
+		return NewInputData(rng.IntN(len(c.current.CardsHeld))+1, c.current.Index)
+	}
+	return NewInputData(0, 0)
+}
+
+func (c *Controller) policyAction(rng *rand.Rand) Action {
+	p := c.current
+	switch {
+	case p.Coins >= 7:
+		return Coup
+	case slices.Contains(p.CardsHeld, Duke):
+		return Tax
+	case slices.Contains(p.CardsHeld, Assassin) && p.Coins >= 3:
+		return Assassinate
+	case slices.Contains(p.CardsHeld, Captain):
+		return Steal
+	}
+	return [3]Action{Income, ForeignAid, Tax}[rng.IntN(3)]
+}
+
+// policyChallenges challenges claims more often the more copies of the card
+// the player can account for, and always once all three are.
+func (c *Controller) policyChallenges(p *Player, card Card, rng *rand.Rand) bool {
+	var accounted int
+	for _, held := range p.CardsHeld {
+		if held == card {
+			accounted++
+		}
+	}
+	for _, other := range c.AllPlayers {
+		for _, lost := range other.CardsLost {
+			if lost == card {
+				accounted++
+			}
+		}
+	}
+	if accounted >= 3 {
+		return true
+	}
+	return rng.IntN(100) < 10+20*accounted
+}
@@ -0,0 +1,100 @@
+package game
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestViewIsRedacted(t *testing.T) {
+	testCon := setupTestController()
+	view := testCon.View(1)
+	for _, p := range view.Players {
+		if p.Index == 1 {
+			assertEqual[int](t, len(p.CardsHeld), 2, "own hand")
+			continue
+		}
+		assertEqual[int](t, len(p.CardsHeld), 0, p.Name+" hand")
+		assertEqual[int](t, p.HandSize, 2, p.Name+" hand size")
+	}
+	assertEqual[int](t, len(view.Unseen()), 13, "unseen cards")
+}
+
+func TestSpectatorViews(t *testing.T) {
+	testCon := setupTestController()
+	view := testCon.View(Spectator)
+	for _, p := range view.Players {
+		assertEqual[int](t, len(p.CardsHeld), 0, p.Name+" hand seen by spectator")
+	}
+	assertEqual[bool](t, view.DisplayData().Spectating, true, "spectating")
+
+	revealed := testCon.RevealedView()
+	for _, p := range revealed.Players {
+		assertEqual[int](t, len(p.CardsHeld), 2, p.Name+" revealed hand")
+	}
+	assertEqual[bool](t, revealed.DisplayData().Revealed, true, "revealed")
+	assertEqual[int](t, len(revealed.DisplayData().CardCounts), 0, "card counts when revealed")
+}
+
+func TestViewMoveOutlastsState(t *testing.T) {
+	testCon := setupTestController()
+	before := testCon.View(0)
+	for i := range testCon.AllPlayers {
+		testCon.UpdateGame(NewInputData(int(Income), i))
+		testCon.UpdateGame(NewInputData(0, i))
+	}
+	after := testCon.View(0)
+	assertEqual[State](t, after.State, before.State, "state after a round of Income")
+	assertEqual[int](t, after.Move, before.Move+2*len(testCon.AllPlayers), "moves after a round of Income")
+}
+
+func TestLegalOptionsForcedCoup(t *testing.T) {
+	testCon := setupTestController()
+	testCon.AllPlayers[0].Coins = 10
+	options := LegalOptions(testCon.View(0))
+	assertEqual[int](t, len(options), 1, "forced coup options")
+	assertEqual[int](t, options[0].Key, int(Coup), "forced coup key")
+	assertEqual[int](t, len(LegalOptions(testCon.View(1))), 0, "inactive seat options")
+}
+
+func TestAdviseFindsWinningCoup(t *testing.T) {
+	testCon := setupTestController()
+	for _, p := range testCon.AllPlayers[2:] {
+		p.CardsLost = append(p.CardsLost, p.CardsHeld...)
+		p.CardsHeld = nil
+	}
+	// Both survivors are down to one card with enough coins to Coup, so
+	// whoever doesn't Coup now loses next turn.
+	for _, p := range testCon.AllPlayers[:2] {
+		p.CardsLost = append(p.CardsLost, p.CardsHeld[0])
+		p.CardsHeld = p.CardsHeld[1:]
+		p.Coins = 7
+	}
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	options := Advise(testCon.View(0), 20, rng)
+	assertEqual[int](t, options[0].Key, int(Coup), "suggested move")
+	assertEqual[float64](t, options[0].WinRate, 1, "coup win rate")
+}
+
+func TestCardCounts(t *testing.T) {
+	testCon := setupTestController()
+	testCon.AllPlayers[0].CardsHeld = []Card{Duke, Duke}
+	bob := testCon.AllPlayers[1]
+	bob.CardsLost = []Card{Duke}
+	bob.CardsHeld = bob.CardsHeld[:1]
+	counts := testCon.View(0).CardCounts()
+	for _, count := range counts {
+		if count.Card != Duke {
+			continue
+		}
+		assertEqual[int](t, count.Unseen, 0, "unseen dukes")
+		assertEqual[float64](t, count.Odds[1], 0, "bob duke odds")
+	}
+	// Of the 12 unseen cards, 3 are Contessas, so a 2 card hand misses all of
+	// them with chance 9/12 * 8/11.
+	want := 1 - (9.0/12)*(8.0/11)
+	got := counts[3].Odds[2]
+	if got < want-1e-9 || got > want+1e-9 {
+		t.Errorf("contessa odds: got %v, want %v", got, want)
+	}
+}
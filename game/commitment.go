@@ -0,0 +1,217 @@
+package game
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	mrand "math/rand/v2"
+	"slices"
+)
+
+// Seed is what the court deck is shuffled from. A host commits to it at the
+// start of a game by publishing its hash, the Commitment, and reveals it at
+// the end in a ShuffleRecord. As the seed can't be changed once the hash is
+// out, anyone can then check that every card was dealt as the seed says.
+//
+// So that the host can't pick the seed either, it can be made from
+// Contributions instead: entropy from the host and from each player, every
+// one of them committed to before any is revealed.
+type Seed [32]byte
+
+func NewSeed() Seed {
+	var seed Seed
+	rand.Read(seed[:])
+	return seed
+}
+
+// Commitment is the SHA-256 hash of the seed, in hex.
+func (s Seed) Commitment() string {
+	sum := sha256.Sum256(s[:])
+	return hex.EncodeToString(sum[:])
+}
+
+// Contribution is one share of the entropy a seed is made from. Player is
+// the seat it came from, or -1 for the host's own. Commitment is the hash of
+// the entropy, as for a Seed, and Entropy is only filled in, in hex, once it
+// has been revealed.
+type Contribution struct {
+	Player     int
+	Commitment string
+	Entropy    string `json:",omitempty"`
+}
+
+// parseSeed reads a seed written out in hex.
+func parseSeed(text string) (Seed, error) {
+	raw, err := hex.DecodeString(text)
+	if err != nil || len(raw) != len(Seed{}) {
+		return Seed{}, errors.New("the seed isn't 32 bytes of hex")
+	}
+	return Seed(raw), nil
+}
+
+// Check reports whether the contribution's entropy is the one committed to.
+func (con Contribution) Check() error {
+	entropy, err := parseSeed(con.Entropy)
+	if err != nil {
+		return fmt.Errorf("player %d's entropy: %w", con.Player+1, err)
+	}
+	if entropy.Commitment() != con.Commitment {
+		return fmt.Errorf("player %d's entropy doesn't match their commitment", con.Player+1)
+	}
+	return nil
+}
+
+// CombineSeed checks every revealed contribution against its commitment, and
+// returns the seed they make together: the SHA-256 hash of all their
+// entropy, in order.
+func CombineSeed(contributions []Contribution) (Seed, error) {
+	if len(contributions) == 0 {
+		return Seed{}, errors.New("there is nothing to make a seed from")
+	}
+	h := sha256.New()
+	for _, con := range contributions {
+		if err := con.Check(); err != nil {
+			return Seed{}, err
+		}
+		entropy, _ := parseSeed(con.Entropy)
+		h.Write(entropy[:])
+	}
+	return Seed(h.Sum(nil)), nil
+}
+
+// Reseed shuffles the deck from the seed the contributions make instead of
+// the Controller's own, and keeps them for the ShuffleRecord. It must be
+// called before ShuffleAndDeal.
+func (c *Controller) Reseed(contributions []Contribution) error {
+	seed, err := CombineSeed(contributions)
+	if err != nil {
+		return err
+	}
+	c.seed = seed
+	c.rng = mrand.New(mrand.NewChaCha8(seed))
+	c.contributions = slices.Clone(contributions)
+	return nil
+}
+
+// DeckEvent is a card going to a player from the deck, or back into it if
+// Returned is set.
+type DeckEvent struct {
+	Player   int
+	Card     Card
+	Returned bool
+}
+
+// ShuffleRecord is everything needed to check the deck after a game: the
+// seed, the commitment made to it, the contributions it was made from if it
+// was, and every card that left or rejoined the deck, in order.
+type ShuffleRecord struct {
+	Players       int
+	Commitment    string
+	Seed          string
+	Contributions []Contribution `json:",omitempty"`
+	Events        []DeckEvent
+}
+
+// Commitment is the hash of the seed the deck was shuffled from, which can be
+// published before the game starts. It is empty if the deck is kept by a
+// Dealer, as there is no seed.
+func (c *Controller) Commitment() string {
+	if c.dealer != nil {
+		return ""
+	}
+	return c.seed.Commitment()
+}
+
+// ShuffleRecord reveals the seed along with every draw and return. It gives
+// away every card dealt, so should only be handed out once the game is over.
+// It is nil if the deck was kept by a Dealer.
+func (c *Controller) ShuffleRecord() *ShuffleRecord {
+	if c.dealer != nil {
+		return nil
+	}
+	return &ShuffleRecord{
+		Players:       len(c.AllPlayers),
+		Commitment:    c.seed.Commitment(),
+		Seed:          hex.EncodeToString(c.seed[:]),
+		Contributions: slices.Clone(c.contributions),
+		Events:        slices.Clone(c.deckLog),
+	}
+}
+
+func (c *Controller) logDeck(event DeckEvent) {
+	if !c.simulated {
+		c.deckLog = append(c.deckLog, event)
+	}
+}
+
+// VerifyShuffle checks a record against its commitment, and against the
+// contributions the seed was made from if it has them. Then it shuffles and
+// deals a deck from its seed exactly as the Controller does, returning the
+// cards each player gave back at the same points, and checks that every card
+// drawn is the one in the record.
+func VerifyShuffle(r *ShuffleRecord) error {
+	seed, err := parseSeed(r.Seed)
+	if err != nil {
+		return err
+	}
+	if seed.Commitment() != r.Commitment {
+		return errors.New("the seed doesn't match the commitment")
+	}
+	if len(r.Contributions) > 0 {
+		combined, err := CombineSeed(r.Contributions)
+		if err != nil {
+			return err
+		}
+		if combined != seed {
+			return errors.New("the seed isn't the one its contributions make")
+		}
+	}
+	if r.Players < 3 || r.Players > 6 {
+		return fmt.Errorf("a game has 3 to 6 players, not %d", r.Players)
+	}
+
+	var players []*Player
+	for i := range r.Players {
+		p, _ := NewPlayer(fmt.Sprintf("Player %d", i+1), i, false, false)
+		players = append(players, p)
+	}
+	c := NewController(players)
+	c.seed = seed
+	c.rng = mrand.New(mrand.NewChaCha8(seed))
+	c.shuffle()
+	c.deal()
+	for i, event := range r.Events {
+		if i < len(c.deckLog) {
+			// Still in the deal.
+			continue
+		}
+		if event.Player < 0 || event.Player >= r.Players {
+			return fmt.Errorf("event %d: there is no player %d", i+1, event.Player)
+		}
+		p := players[event.Player]
+		if !event.Returned {
+			if len(c.deck) == 0 {
+				return fmt.Errorf("event %d: player %d draws from an empty deck", i+1, event.Player+1)
+			}
+			c.draw(p)
+			continue
+		}
+		held := slices.Index(p.CardsHeld, event.Card)
+		if held < 0 {
+			return fmt.Errorf("event %d: player %d returned a %s they weren't holding", i+1, event.Player+1, event.Card.Name())
+		}
+		p.CardsHeld = slices.Delete(p.CardsHeld, held, held+1)
+		c.returnCard(p, event.Card)
+	}
+	if len(c.deckLog) != len(r.Events) {
+		return fmt.Errorf("the record has %d events, but the seed deals %d", len(r.Events), len(c.deckLog))
+	}
+	for i, want := range c.deckLog {
+		if got := r.Events[i]; got != want {
+			return fmt.Errorf("event %d: the record has player %d drawing %s, but the seed deals player %d %s", i+1, got.Player+1, got.Card.Name(), want.Player+1, want.Card.Name())
+		}
+	}
+	return nil
+}
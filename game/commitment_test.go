@@ -0,0 +1,148 @@
+package game
+
+import (
+	"encoding/hex"
+	"slices"
+	"testing"
+)
+
+func dealtController() *Controller {
+	var players []*Player
+	for i, name := range []string{"Alice", "Bob", "Charlie", "Diana"} {
+		p, _ := NewPlayer(name, i, false, false)
+		players = append(players, p)
+	}
+	c := NewController(players)
+	c.ShuffleAndDeal()
+	return c
+}
+
+func TestShuffleRecordVerifies(t *testing.T) {
+	c := dealtController()
+	c.swapCard(1, 0)
+	c.current = c.AllPlayers[2]
+	c.exchangeDrawTwo()
+	c.returnCard(c.current, c.current.CardsHeld[0])
+	c.returnCard(c.current, c.current.CardsHeld[3])
+
+	record := c.ShuffleRecord()
+	assertEqual[int](t, len(record.Events), 8+2+2+2, "deck events")
+	if err := VerifyShuffle(record); err != nil {
+		t.Errorf("honest record: %v", err)
+	}
+	assertEqual[string](t, c.View(0).Commitment, record.Commitment, "commitment in view")
+}
+
+func TestShuffleRecordCatchesTampering(t *testing.T) {
+	c := dealtController()
+	record := c.ShuffleRecord()
+	record.Events[3].Card = (record.Events[3].Card % Duke) + 1
+	if VerifyShuffle(record) == nil {
+		t.Errorf("record with a changed card verified")
+	}
+
+	record = c.ShuffleRecord()
+	record.Seed = NewSeed().Commitment()
+	if VerifyShuffle(record) == nil {
+		t.Errorf("record with another seed verified")
+	}
+}
+
+func contributions(n int) []Contribution {
+	var out []Contribution
+	for i := range n {
+		entropy := NewSeed()
+		out = append(out, Contribution{
+			Player:     i - 1,
+			Commitment: entropy.Commitment(),
+			Entropy:    hex.EncodeToString(entropy[:]),
+		})
+	}
+	return out
+}
+
+func TestContributionsMakeTheSeed(t *testing.T) {
+	var players []*Player
+	for i, name := range []string{"Alice", "Bob", "Charlie"} {
+		p, _ := NewPlayer(name, i, false, false)
+		players = append(players, p)
+	}
+	c := NewController(players)
+	cons := contributions(3)
+	if err := c.Reseed(cons); err != nil {
+		t.Fatalf("Reseed: %v", err)
+	}
+	c.ShuffleAndDeal()
+	if err := VerifyShuffle(c.ShuffleRecord()); err != nil {
+		t.Errorf("honest record: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(r *ShuffleRecord)
+	}{
+		{"entropy", func(r *ShuffleRecord) { r.Contributions[1].Entropy = cons[2].Entropy }},
+		{"dropped", func(r *ShuffleRecord) { r.Contributions = r.Contributions[1:] }},
+		{"reordered", func(r *ShuffleRecord) {
+			r.Contributions[0], r.Contributions[1] = r.Contributions[1], r.Contributions[0]
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			record := c.ShuffleRecord()
+			tc.tamper(record)
+			if VerifyShuffle(record) == nil {
+				t.Errorf("record with tampered contributions verified")
+			}
+		})
+	}
+}
+
+func TestReseedChecksCommitments(t *testing.T) {
+	c := dealtController()
+	cons := contributions(2)
+	cons[1].Commitment = cons[0].Commitment
+	if c.Reseed(cons) == nil {
+		t.Errorf("entropy that doesn't match its commitment was taken")
+	}
+}
+
+func TestWitnessChecksTheDealPlayed(t *testing.T) {
+	c := dealtController()
+	dealt := slices.Clone(c.AllPlayers[0].CardsHeld)
+	var w Witness
+	w.See(c.View(0))
+	shown := c.AllPlayers[1].CardsHeld[0]
+	c.recordReveal(1, shown, shown)
+	c.swapCard(1, 0)
+	c.loseCard(0, 0)
+	w.See(c.View(0))
+	if err := w.Check(c.ShuffleRecord()); err != nil {
+		t.Errorf("record of the deal seen: %v", err)
+	}
+
+	// A host dealing from a stacked deck can still hand out an honest
+	// record for the seed it committed to.
+	stacked := dealtController()
+	for sameCards(stacked.AllPlayers[0].CardsHeld, dealt) {
+		stacked = dealtController()
+	}
+	stacked.seed = c.seed
+	w = Witness{}
+	w.See(stacked.View(0))
+	record := c.ShuffleRecord()
+	if err := VerifyShuffle(record); err != nil {
+		t.Fatalf("honest record: %v", err)
+	}
+	if w.Check(record) == nil {
+		t.Errorf("record of another deal checked out against the hand dealt")
+	}
+
+	// Nor can it have some other card go back in place of one shown to win a challenge.
+	record.Events[8].Card = (shown % Duke) + 1
+	w = Witness{}
+	w.See(c.View(Spectator))
+	if w.Check(record) == nil {
+		t.Errorf("record returning another card than the one shown checked out")
+	}
+}
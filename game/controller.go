@@ -17,6 +17,12 @@ var(
 	debug = log.New(logFile, "[DEBUG]", log.Lshortfile)
 )
 
+// Debugf writes a line to debug.log, so the other packages can share the one
+// log file rather than each truncating their own.
+func Debugf(format string, v ...any) {
+	debug.Output(2, fmt.Sprintf(format, v...))
+}
+
 type Queue[E any] interface {
 	Enqueue(E)
 	Dequeue() E
@@ -112,15 +118,24 @@ type Controller struct {
 	selection     int
 	playerIndex   int
 	exchangeDrawn bool
+	history       []Event
+	turn          int
+	moves         int
+	simulated     bool
+	dealer        Dealer
+	dealerErr     error
+	seed          Seed
+	contributions []Contribution
+	deckLog       []DeckEvent
 }
 
 func NewController(players []*Player) *Controller {
 	var newDeck []Card
-	rngOut := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	seed := NewSeed()
+	rngOut := rand.New(rand.NewChaCha8(seed))
 	actionLog := NewActionLog(10)
 	stateIn := State{Phase: SelectAction, Action: NoAction}
-	allCards := [5]Card{Ambassador, Assassin, Captain, Contessa, Duke}
-	for _, c := range allCards {
+	for _, c := range AllCards {
 		for range 3 {
 			newDeck = append(newDeck, c)
 		}
@@ -131,13 +146,34 @@ func NewController(players []*Player) *Controller {
 		AllPlayers:    players,
 		activePlayers: []*Player{players[0]},
 		rng:           rngOut,
+		seed:          seed,
 		deck:          newDeck,
 		current:       players[0],
 	}
 	return &cOut
 }
 
+// Moves counts the inputs the game has taken, so that anything worked out
+// for one of them, such as a hint, can tell when it is out of date.
+func (c *Controller) Moves() int {
+	return c.moves
+}
+
+// Note adds a line to the action log about something outside the game
+// itself, such as a player losing their connection.
+func (c *Controller) Note(msg string) {
+	c.actionLog.Enqueue(msg)
+}
+
 func (c *Controller) ShuffleAndDeal() {
+	if c.dealer != nil {
+		// The Dealer has shuffled already, so just deal from it.
+		for _, p := range c.AllPlayers {
+			c.draw(p)
+			c.draw(p)
+		}
+		return
+	}
 	c.shuffle()
 	c.deal()
 }
@@ -153,6 +189,8 @@ func (c *Controller) deal() {
 		n := c.rng.IntN(len(c.deck) - 1)
 		p.CardsHeld = append(p.CardsHeld, c.deck[n])
 		p.CardsHeld = append(p.CardsHeld, c.deck[n+1])
+		c.logDeck(DeckEvent{Player: p.Index, Card: c.deck[n]})
+		c.logDeck(DeckEvent{Player: p.Index, Card: c.deck[n+1]})
 		c.deck = slices.Delete(c.deck, n, n+2)
 	}
 }
@@ -238,17 +276,16 @@ func (c *Controller) swapCard(playerIdx, cardIdx int) {
 	player := c.AllPlayers[playerIdx]
 	card := player.CardsHeld[cardIdx]
 	player.CardsHeld = slices.Delete(player.CardsHeld, cardIdx, cardIdx+1)
-	c.deck = append(c.deck, card)
-	drawIdx := c.rng.IntN(len(c.deck))
-	player.CardsHeld = append(player.CardsHeld, c.deck[drawIdx])
-	c.deck = slices.Delete(c.deck, drawIdx, drawIdx+1)
+	c.returnCard(player, card)
+	c.draw(player)
 }
 
 func (c *Controller) loseCard(playerIdx, cardIdx int) {
 	player := c.AllPlayers[playerIdx]
-	card := player.CardsHeld[cardIdx]
+	card := c.revealCard(player, cardIdx, true)
 	player.CardsHeld = slices.Delete(player.CardsHeld, cardIdx, cardIdx+1)
 	player.CardsLost = append(player.CardsLost, card)
+	c.record(Lost, playerIdx, -1, card)
 }
 
 func (c *Controller) getCurrentCard() Card {
@@ -267,6 +304,12 @@ func (c *Controller) getCurrentCard() Card {
 }
 
 func (c *Controller) UpdateGame(data *InputData) {
+	c.moves++
+	if c.dealerErr != nil {
+		c.State = State{EndGame, NoAction}
+		c.setActivePlayers()
+		return
+	}
 	if c.target != nil && !c.target.IsAlive() {
 		if c.Action == Steal {
 			c.current.Coins += 2
@@ -284,7 +327,9 @@ func (c *Controller) UpdateGame(data *InputData) {
 	c.selection = data.Selection
 	c.playerIndex = data.PlayerIndex
 
-	debug.Printf("state - %v; active - %v; input - %v", c.State, c.activePlayers, *data)
+	if !c.simulated {
+		debug.Printf("state - %v; active - %v; input - %v", c.State, c.activePlayers, *data)
+	}
 	handler := handlers[c.State]
 	newState := handler(c, c.selection, c.playerIndex)
 	c.State = newState
@@ -312,6 +357,7 @@ func (c *Controller) advanceTurn() State {
 	}
 
 	// Now we know that the game will be continuing, we can update for the next turn.
+	c.turn++
 	c.current = c.AllPlayers[pIdx]
 	c.activePlayers = nil
 	c.target = nil
@@ -339,6 +385,8 @@ func (c *Controller) selectAction(sel, pIdx int) State {
 	if nextAction != Assassinate && nextAction != Coup && nextAction != Steal {
 		toLog := fmt.Sprintf("%s has selected %s", c.current, nextAction)
 		c.actionLog.Enqueue(toLog)
+		c.Action = nextAction
+		c.record(Declared, c.current.Index, -1, nextAction.Card())
 	}
 	switch nextAction {
 	case Assassinate, Coup, Steal:
@@ -361,6 +409,7 @@ func (c *Controller) selectTarget(sel, pIdx int) State {
 	// 1 now to correct.
 	validTargets := c.getValidTargets()
 	c.target = validTargets[sel-1]
+	c.record(Declared, c.current.Index, c.target.Index, c.Action.Card())
 	if c.Action == Steal {
 		toLog := fmt.Sprintf("%s is attempting to %s from %s", c.current, c.Action, c.target)
 		c.actionLog.Enqueue(toLog)
@@ -383,6 +432,7 @@ func (c *Controller) makeChallenge(sel, pIdx int) State {
 	if sel == 0 {
 		currentCard := c.getCurrentCard()
 		c.actionLog.Enqueue(fmt.Sprintf("No one dares challenge %s's %s claim", c.current, currentCard))
+		c.recordPasses(Passed, c.current.Index, currentCard)
 		switch c.Action {
 		case Assassinate, Steal:
 			return State{Phase: MakeBlock, Action: c.Action}
@@ -391,6 +441,7 @@ func (c *Controller) makeChallenge(sel, pIdx int) State {
 		}
 	}
 	c.challenger = c.AllPlayers[pIdx]
+	c.record(Challenged, pIdx, c.current.Index, c.Action.Card())
 	toLog := fmt.Sprintf(
 		"%s is challenging the %s claim of %s",
 		c.challenger,
@@ -402,7 +453,7 @@ func (c *Controller) makeChallenge(sel, pIdx int) State {
 }
 
 func (c *Controller) challengeReveal(sel, pIdx int) State {
-	revealedCard := c.current.CardsHeld[sel]
+	revealedCard := c.revealCard(c.current, sel, false)
 	var toLogFail = fmt.Sprintf(
 		"Challenge fails! %s shuffles %s into the deck and draws a new card",
 		c.current,
@@ -414,6 +465,7 @@ func (c *Controller) challengeReveal(sel, pIdx int) State {
 		revealedCard,
 	)
 	c.actionLog.Enqueue(fmt.Sprintf("%s reveals... %s!", c.current, revealedCard))
+	c.recordReveal(c.current.Index, c.Action.Card(), revealedCard)
 	// Use the switch statement to check if the challenge fails.
 	// If it does, swap the current player's card and get the
 	// challenger to lose a card. Else, lose the revealed card
@@ -478,6 +530,7 @@ func (c *Controller) challengeLoss(sel, pIdx int) State {
 
 func (c *Controller) makeBlock(sel, pIdx int) State {
 	if sel == 0 {
+		c.recordPasses(Allowed, c.current.Index, NoCard)
 		return State{Phase: ResolveAction, Action: c.Action}
 	}
 	if sel == 1 && c.Action == Steal {
@@ -485,6 +538,7 @@ func (c *Controller) makeBlock(sel, pIdx int) State {
 		c.blockType = Ambassador
 		toLog := fmt.Sprintf("%s is claiming %s to block %s's %s", c.blocker, c.blockType, c.current, c.Action)
 		c.actionLog.Enqueue(toLog)
+		c.record(Blocked, pIdx, c.current.Index, c.blockType)
 		return State{Phase: ChallengeBlock, Action: c.Action}
 	}
 	if sel == 2 && c.Action == Steal {
@@ -492,6 +546,7 @@ func (c *Controller) makeBlock(sel, pIdx int) State {
 		c.blockType = Captain
 		toLog := fmt.Sprintf("%s is claiming %s to block %s's %s", c.blocker, c.blockType, c.current, c.Action)
 		c.actionLog.Enqueue(toLog)
+		c.record(Blocked, pIdx, c.current.Index, c.blockType)
 		return State{Phase: ChallengeBlock, Action: c.Action}
 	}
 	if sel == 1 {
@@ -504,6 +559,7 @@ func (c *Controller) makeBlock(sel, pIdx int) State {
 		}
 		toLog := fmt.Sprintf("%s is claiming %s to block %s's %s", c.blocker, c.blockType, c.current, c.Action)
 		c.actionLog.Enqueue(toLog)
+		c.record(Blocked, pIdx, c.current.Index, c.blockType)
 		return State{Phase: ChallengeBlock, Action: c.Action}
 	}
 	panic("Unreachable code! (makeBlock)")
@@ -513,10 +569,12 @@ func (c *Controller) challengeBlock(sel, pIdx int) State {
 	// An unchallenged block ends the turn.
 	if sel == 0 {
 		c.actionLog.Enqueue(fmt.Sprintf("%s successfully blocks %s's %s attempt!", c.blocker, c.current, c.Action))
+		c.recordPasses(Passed, c.blocker.Index, c.blockType)
 		return c.advanceTurn()
 	}
 
 	c.challenger = c.AllPlayers[pIdx]
+	c.record(Challenged, pIdx, c.blocker.Index, c.blockType)
 	toLog := fmt.Sprintf(
 		"%s is challenging the %s claim of %s",
 		c.challenger,
@@ -528,8 +586,9 @@ func (c *Controller) challengeBlock(sel, pIdx int) State {
 }
 
 func (c *Controller) blockReveal(sel, pIdx int) State {
-	revealedCard := c.blocker.CardsHeld[sel]
+	revealedCard := c.revealCard(c.blocker, sel, false)
 	c.actionLog.Enqueue(fmt.Sprintf("%s reveals... %s!", c.blocker, revealedCard))
+	c.recordReveal(c.blocker.Index, c.blockType, revealedCard)
 	// Same as challengeReveal, except a failed challenge always leads to
 	// action resolution, simplifying significantly.
 	if revealedCard == c.blockType {
@@ -619,7 +678,9 @@ func (c *Controller) exchangeFinal(sel, pIdx int) State {
 	c.returnedCards = append(c.returnedCards, c.current.CardsHeld[sel-1])
 	c.current.CardsHeld = slices.Delete(c.current.CardsHeld, sel-1, sel)
 	// Now we can put the returned cards back in the deck.
-	c.deck = append(c.deck, c.returnedCards...)
+	for _, card := range c.returnedCards {
+		c.returnCard(c.current, card)
+	}
 	c.actionLog.Enqueue(fmt.Sprintf("%s returns 2 chosen cards to the deck", c.current))
 	return c.advanceTurn()
 }
@@ -631,8 +692,6 @@ func (c *Controller) endGame(sel, pIdx int) State {
 
 func (c *Controller) exchangeDrawTwo() {
 	for range 2 {
-		n := c.rng.IntN(len(c.deck))
-		c.current.CardsHeld = append(c.current.CardsHeld, c.deck[n])
-		c.deck = slices.Delete(c.deck, n, n+1)
+		c.draw(c.current)
 	}
 }
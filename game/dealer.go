@@ -0,0 +1,152 @@
+package game
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Dealer keeps the court deck in place of the Controller, for when the cards
+// have to be dealt some other way than by the Controller's own shuffle, such
+// as by the players between themselves. An error from any of its methods
+// means a player cheated, and ends the game.
+type Dealer interface {
+	// Draw takes the top card of the deck for the player. It is a FaceDown
+	// card if only the player may know what it is.
+	Draw(player int) (Card, error)
+	// Return puts a card the player holds back in the deck and shuffles it.
+	Return(player int, card Card) error
+	// Reveal shows everyone a card the player holds, and returns what it is.
+	Reveal(player int, card Card) (Card, error)
+	// Lose turns one of the player's cards face up for good, and returns
+	// what it is.
+	Lose(player int, card Card) (Card, error)
+}
+
+// faceDown is where the FaceDown cards start, well clear of the roles.
+const faceDown Card = 1 << 20
+
+// FaceDown is the nth card a Dealer has dealt without showing it to the
+// Controller. The number only means something to the Dealer and to the
+// player who holds the card, who can turn it over on their own side.
+func FaceDown(n int) Card {
+	return faceDown + Card(n)
+}
+
+// FaceDown reports whether the card was dealt face down, and if so its
+// number.
+func (c Card) FaceDown() (int, bool) {
+	if c < faceDown {
+		return 0, false
+	}
+	return int(c - faceDown), true
+}
+
+// SetDealer hands the deck over to d. It must be called before
+// ShuffleAndDeal.
+func (c *Controller) SetDealer(d Dealer) {
+	c.dealer = d
+}
+
+// DealerErr is why the Dealer stopped the game, if it did.
+func (c *Controller) DealerErr() error {
+	return c.dealerErr
+}
+
+// draw gives the player the top card of the deck.
+func (c *Controller) draw(player *Player) {
+	if c.dealer == nil {
+		n := c.rng.IntN(len(c.deck))
+		player.CardsHeld = append(player.CardsHeld, c.deck[n])
+		c.logDeck(DeckEvent{Player: player.Index, Card: c.deck[n]})
+		c.deck = slices.Delete(c.deck, n, n+1)
+		return
+	}
+	card, err := c.dealer.Draw(player.Index)
+	if err != nil {
+		c.dealerFailed(err)
+		return
+	}
+	// The Controller's deck only counts the cards the Dealer has, as its
+	// order is the Dealer's secret. A card dealt face down still counts
+	// until it is shown, as nobody here knows which it was.
+	if _, ok := card.FaceDown(); !ok && !c.takeFromDeck(card) {
+		return
+	}
+	player.CardsHeld = append(player.CardsHeld, card)
+}
+
+// takeFromDeck takes a card the Dealer has shown out of the Controller's
+// count of the deck.
+func (c *Controller) takeFromDeck(card Card) bool {
+	n := slices.Index(c.deck, card)
+	if n < 0 {
+		c.dealerFailed(fmt.Errorf("the dealer dealt a %s with none left in the deck", card))
+		return false
+	}
+	c.deck = slices.Delete(c.deck, n, n+1)
+	return true
+}
+
+// deckSize is how many cards are left in the deck. The Controller's count
+// still holds the cards dealt face down, so they are taken off here.
+func (c *Controller) deckSize() int {
+	n := len(c.deck)
+	hidden := slices.Clone(c.returnedCards)
+	for _, p := range c.AllPlayers {
+		hidden = append(hidden, p.CardsHeld...)
+	}
+	for _, card := range hidden {
+		if _, ok := card.FaceDown(); ok {
+			n--
+		}
+	}
+	return n
+}
+
+// returnCard puts a card the player has given up back in the deck.
+func (c *Controller) returnCard(player *Player, card Card) {
+	if c.dealer == nil {
+		c.deck = append(c.deck, card)
+		c.logDeck(DeckEvent{Player: player.Index, Card: card, Returned: true})
+		return
+	}
+	if err := c.dealer.Return(player.Index, card); err != nil {
+		c.dealerFailed(err)
+		return
+	}
+	if _, ok := card.FaceDown(); !ok {
+		c.deck = append(c.deck, card)
+	}
+}
+
+// revealCard shows the card at idx in the player's hand to everyone, checking
+// it with the Dealer if there is one, and returns it. A card dealt face down
+// is only known from here on. A lost card stays face up.
+func (c *Controller) revealCard(player *Player, idx int, lost bool) Card {
+	card := player.CardsHeld[idx]
+	if c.dealer == nil {
+		return card
+	}
+	reveal := c.dealer.Reveal
+	if lost {
+		reveal = c.dealer.Lose
+	}
+	shown, err := reveal(player.Index, card)
+	if err != nil {
+		c.dealerFailed(err)
+		return card
+	}
+	if _, ok := card.FaceDown(); ok && !c.takeFromDeck(shown) {
+		return card
+	}
+	player.CardsHeld[idx] = shown
+	return shown
+}
+
+func (c *Controller) dealerFailed(err error) {
+	if c.dealerErr != nil {
+		return
+	}
+	c.dealerErr = err
+	c.actionLog.Enqueue(fmt.Sprintf("The game is stopped: %v", err))
+}
@@ -0,0 +1,176 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// stackDealer deals a stack of cards top first, putting returned cards at the
+// bottom, and keeps track of who holds what so that it can refuse a reveal of
+// a card the player wasn't dealt, as a real Dealer would. The players in
+// hidden are dealt their cards face down.
+type stackDealer struct {
+	stack    []Card
+	held     map[int][]Card
+	lost     []Card
+	hidden   map[int]bool
+	faceDown []Card
+}
+
+func newStackDealer(stack ...Card) *stackDealer {
+	return &stackDealer{stack: stack, held: map[int][]Card{}}
+}
+
+func (d *stackDealer) Draw(player int) (Card, error) {
+	if len(d.stack) == 0 {
+		return NoCard, errors.New("the deck is empty")
+	}
+	card := d.stack[0]
+	d.stack = d.stack[1:]
+	if d.hidden[player] {
+		d.faceDown = append(d.faceDown, card)
+		card = FaceDown(len(d.faceDown) - 1)
+	}
+	d.held[player] = append(d.held[player], card)
+	return card, nil
+}
+
+// shown is the card under a face-down one.
+func (d *stackDealer) shown(card Card) Card {
+	if n, ok := card.FaceDown(); ok {
+		return d.faceDown[n]
+	}
+	return card
+}
+
+func (d *stackDealer) take(player int, card Card) error {
+	i := slices.Index(d.held[player], card)
+	if i < 0 {
+		return fmt.Errorf("player %d doesn't hold a %s", player, card)
+	}
+	d.held[player] = slices.Delete(d.held[player], i, i+1)
+	return nil
+}
+
+func (d *stackDealer) Return(player int, card Card) error {
+	if err := d.take(player, card); err != nil {
+		return err
+	}
+	d.stack = append(d.stack, d.shown(card))
+	return nil
+}
+
+func (d *stackDealer) Reveal(player int, card Card) (Card, error) {
+	i := slices.Index(d.held[player], card)
+	if i < 0 {
+		return NoCard, fmt.Errorf("player %d doesn't hold a %s", player, card)
+	}
+	d.held[player][i] = d.shown(card)
+	return d.held[player][i], nil
+}
+
+func (d *stackDealer) Lose(player int, card Card) (Card, error) {
+	if err := d.take(player, card); err != nil {
+		return NoCard, err
+	}
+	d.lost = append(d.lost, d.shown(card))
+	return d.shown(card), nil
+}
+
+func setupDealerController(d Dealer) *Controller {
+	var players []*Player
+	for i, name := range []string{"Alice", "Bob", "Charlie"} {
+		p, _ := NewPlayer(name, i, false, false)
+		players = append(players, p)
+	}
+	c := NewController(players)
+	c.SetDealer(d)
+	c.ShuffleAndDeal()
+	return c
+}
+
+func TestControllerPlaysThroughDealer(t *testing.T) {
+	d := newStackDealer(
+		Contessa, Contessa, Captain, Ambassador, Duke, Duke,
+		Ambassador, Captain, Assassin, Assassin, Assassin, Contessa, Duke, Ambassador, Captain,
+	)
+	c := setupDealerController(d)
+	assertError(t, "dealing", c.DealerErr())
+	alice, bob := c.AllPlayers[0], c.AllPlayers[1]
+	assertEqual[Card](t, alice.CardsHeld[1], Contessa, "Alice's second card")
+	assertEqual[Card](t, bob.CardsHeld[0], Captain, "Bob's first card")
+	assertEqual[int](t, c.View(Spectator).DeckSize, 9, "deck after the deal")
+	assertEqual[string](t, c.Commitment(), "", "commitment with a dealer")
+
+	// Alice exchanges both her Contessas for the Ambassador and Captain on
+	// top of the deck.
+	for _, in := range [][2]int{{5, 0}, {0, 0}, {0, 0}} {
+		c.UpdateGame(NewInputData(in[0], in[1]))
+	}
+	assertEqual[Phase](t, c.Phase, ExchangeMiddle, "phase after drawing two")
+	assertEqual[int](t, c.View(Spectator).DeckSize, 7, "deck while exchanging")
+	c.UpdateGame(NewInputData(0, 0))
+	c.UpdateGame(NewInputData(1, 0))
+	assertEqual[Phase](t, c.Phase, SelectAction, "phase after the exchange")
+	assertEqual[int](t, c.View(Spectator).DeckSize, 9, "deck after the exchange")
+	if !slices.Equal(alice.CardsHeld, []Card{Ambassador, Captain}) || !slices.Equal(d.stack[len(d.stack)-2:], []Card{Contessa, Contessa}) {
+		t.Errorf("after the exchange Alice holds %v and the deck ends %v, want Ambassador and Captain, and Contessas", alice.CardsHeld, d.stack)
+	}
+
+	// Bob bluffs Tax, is challenged and gives up his Captain.
+	for _, in := range [][2]int{{7, 1}, {1, 2}, {0, 1}} {
+		c.UpdateGame(NewInputData(in[0], in[1]))
+	}
+	assertError(t, "playing", c.DealerErr())
+	if !slices.Equal(bob.CardsLost, []Card{Captain}) || !slices.Equal(d.lost, []Card{Captain}) {
+		t.Errorf("Bob lost %v and the dealer saw %v, want his Captain", bob.CardsLost, d.lost)
+	}
+}
+
+func TestControllerStopsWhenDealerRefuses(t *testing.T) {
+	d := newStackDealer(
+		Contessa, Contessa, Captain, Ambassador, Duke, Duke,
+		Ambassador, Captain, Assassin, Assassin, Assassin, Contessa, Duke, Ambassador, Captain,
+	)
+	c := setupDealerController(d)
+	// The dealer has no record of the card Alice is about to show.
+	d.held[0] = nil
+	for _, in := range [][2]int{{7, 0}, {1, 1}, {0, 0}} {
+		c.UpdateGame(NewInputData(in[0], in[1]))
+	}
+	if c.DealerErr() == nil {
+		t.Fatalf("Alice revealed a card the dealer didn't deal her, and play went on")
+	}
+	c.UpdateGame(NewInputData(0, 0))
+	assertEqual[Phase](t, c.Phase, EndGame, "phase once the dealer refuses")
+}
+
+func TestControllerTurnsOverFaceDownCards(t *testing.T) {
+	d := newStackDealer(
+		Contessa, Contessa, Captain, Ambassador, Duke, Duke,
+		Ambassador, Captain, Assassin, Assassin, Assassin, Contessa, Duke, Ambassador, Captain,
+	)
+	d.hidden = map[int]bool{1: true}
+	c := setupDealerController(d)
+	assertError(t, "dealing", c.DealerErr())
+	bob := c.AllPlayers[1]
+	assertEqual[Card](t, bob.CardsHeld[0], FaceDown(0), "Bob's first card")
+	assertEqual[Card](t, c.View(1).Players[1].CardsHeld[1], FaceDown(1), "Bob's second card in his view")
+	assertEqual[int](t, c.View(Spectator).DeckSize, 9, "deck after the deal")
+
+	// Alice takes Income, then Bob bluffs Tax, is challenged and turns over
+	// his Captain.
+	for _, in := range [][2]int{{1, 0}, {0, 0}, {7, 1}, {1, 2}, {0, 1}} {
+		c.UpdateGame(NewInputData(in[0], in[1]))
+	}
+	assertError(t, "playing", c.DealerErr())
+	if !slices.Equal(bob.CardsLost, []Card{Captain}) || !slices.Equal(d.lost, []Card{Captain}) {
+		t.Errorf("Bob lost %v and the dealer saw %v, want his Captain", bob.CardsLost, d.lost)
+	}
+	history := c.History()
+	assertEqual[Card](t, history[len(history)-2].Shown, Captain, "card shown")
+	assertEqual[Card](t, bob.CardsHeld[0], FaceDown(1), "Bob's card still face down")
+	assertEqual[int](t, c.View(Spectator).DeckSize, 9, "deck once the Captain is shown")
+}
@@ -35,6 +35,12 @@ var actionColor = map[Action]string{
 	Tax:         "\033[35m",
 }
 
+var actionBlockers = map[Action][]Card{
+	ForeignAid:  {Duke},
+	Assassinate: {Contessa},
+	Steal:       {Ambassador, Captain},
+}
+
 var actionCard = map[Action]Card{
 	Assassinate: Assassin,
 	Exchange:    Ambassador,
@@ -50,6 +56,12 @@ func (a Action) Card() Card {
 	return actionCard[a]
 }
 
+// Blockers returns the cards that can block the action, in the order they are
+// offered on the block menu.
+func (a Action) Blockers() []Card {
+	return actionBlockers[a]
+}
+
 type Phase int
 
 const (
@@ -101,6 +113,9 @@ const (
 	Duke
 )
 
+// AllCards lists every role in the game, in the order they are shown.
+var AllCards = [5]Card{Ambassador, Assassin, Captain, Contessa, Duke}
+
 var cardName = map[Card]string{
 	Ambassador: "Ambassador",
 	Assassin:   "Assassin",
@@ -118,9 +133,18 @@ var cardColor = map[Card]string{
 }
 
 func (c Card) String() string {
-	return cardColor[c] + cardName[c] + "\033[0m"
+	return cardColor[c] + c.Name() + "\033[0m"
+}
+
+// Name returns the card's name without any colour codes. A card dealt face
+// down is only Hidden.
+func (c Card) Name() string {
+	if _, ok := c.FaceDown(); ok {
+		return "Hidden"
+	}
+	return cardName[c]
 }
 
 func (c Card) Short() string {
-	return cardColor[c] + strings.ToUpper(cardName[c][:3]) + "\033[0m"
+	return cardColor[c] + strings.ToUpper(c.Name()[:3]) + "\033[0m"
 }
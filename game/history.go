@@ -0,0 +1,80 @@
+package game
+
+// EventKind describes what happened in a single history Event.
+type EventKind int
+
+const (
+	Declared EventKind = iota
+	Blocked
+	Challenged
+	Passed
+	Allowed
+	Revealed
+	Lost
+)
+
+var eventKindName = map[EventKind]string{
+	Declared:   "Declared",
+	Blocked:    "Blocked",
+	Challenged: "Challenged",
+	Passed:     "Passed",
+	Allowed:    "Allowed",
+	Revealed:   "Revealed",
+	Lost:       "Lost",
+}
+
+func (k EventKind) String() string {
+	return eventKindName[k]
+}
+
+// Event is one publicly visible step of the game. Unlike the strings held by
+// ActionLog, the full history is kept for the whole game, so it can be used to
+// build up a picture of how each player behaves.
+//
+// Player is the index of the player acting and Target is the index of the
+// player acted upon, or -1 if there is none. Card is the influence being
+// claimed, challenged or lost. Shown and Proven are only set for Revealed
+// events: Shown is the card turned over, and Proven is true when it backed up
+// the claim on Card.
+type Event struct {
+	Turn   int
+	Kind   EventKind
+	Player int
+	Target int
+	Action Action
+	Card   Card
+	Shown  Card
+	Proven bool
+}
+
+func (c *Controller) record(kind EventKind, player, target int, card Card) {
+	c.history = append(c.history, Event{
+		Turn:   c.turn,
+		Kind:   kind,
+		Player: player,
+		Target: target,
+		Action: c.Action,
+		Card:   card,
+	})
+}
+
+// recordPasses adds an event of the given kind for every player currently
+// allowed to respond, which is how a window closes when nobody takes it.
+func (c *Controller) recordPasses(kind EventKind, target int, card Card) {
+	for _, p := range c.activePlayers {
+		c.record(kind, p.Index, target, card)
+	}
+}
+
+func (c *Controller) recordReveal(player int, claimed, shown Card) {
+	c.record(Revealed, player, -1, claimed)
+	c.history[len(c.history)-1].Shown = shown
+	c.history[len(c.history)-1].Proven = claimed == shown
+}
+
+// History returns a copy of every event recorded so far this game.
+func (c *Controller) History() []Event {
+	out := make([]Event, len(c.history))
+	copy(out, c.history)
+	return out
+}
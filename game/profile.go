@@ -0,0 +1,142 @@
+package game
+
+// priorWeight is how many imaginary observations back up the default rates
+// below. It stops a single bluff or challenge from swinging a profile to an
+// extreme before there is enough history to justify it.
+const priorWeight = 2.0
+
+const (
+	defaultBluffRate     = 0.2
+	defaultChallengeRate = 0.2
+	defaultBlockRate     = 0.5
+)
+
+// Profile is a model of one player built purely from public history. Every
+// map is keyed by the influence involved, except the block maps which are
+// keyed by the action being blocked.
+type Profile struct {
+	Player           int
+	Claims           map[Card]int
+	Proven           map[Card]int
+	Bluffs           map[Card]int
+	ChallengeChances map[Card]int
+	Challenges       map[Card]int
+	BlockChances     map[Action]int
+	Blocks           map[Action]int
+}
+
+func NewProfile(player int) *Profile {
+	profile := Profile{
+		Player:           player,
+		Claims:           map[Card]int{},
+		Proven:           map[Card]int{},
+		Bluffs:           map[Card]int{},
+		ChallengeChances: map[Card]int{},
+		Challenges:       map[Card]int{},
+		BlockChances:     map[Action]int{},
+		Blocks:           map[Action]int{},
+	}
+	return &profile
+}
+
+// BuildProfiles replays a game history and returns one profile per player,
+// indexed by Player.Index.
+func BuildProfiles(numPlayers int, history []Event) []*Profile {
+	profiles := make([]*Profile, numPlayers)
+	for i := range profiles {
+		profiles[i] = NewProfile(i)
+	}
+	for _, e := range history {
+		if e.Player < 0 || e.Player >= numPlayers {
+			continue
+		}
+		profiles[e.Player].observe(e)
+	}
+	return profiles
+}
+
+func (p *Profile) observe(e Event) {
+	switch e.Kind {
+	case Declared:
+		if e.Card != NoCard {
+			p.Claims[e.Card]++
+		}
+	case Blocked:
+		p.Claims[e.Card]++
+		p.BlockChances[e.Action]++
+		p.Blocks[e.Action]++
+	case Allowed:
+		p.BlockChances[e.Action]++
+	case Challenged:
+		p.ChallengeChances[e.Card]++
+		p.Challenges[e.Card]++
+	case Passed:
+		p.ChallengeChances[e.Card]++
+	case Revealed:
+		if e.Proven {
+			p.Proven[e.Card]++
+			return
+		}
+		p.Bluffs[e.Card]++
+	}
+}
+
+// Add folds other, such as a profile of the same player from an earlier
+// game, into p.
+func (p *Profile) Add(other *Profile) {
+	addCounts(p.Claims, other.Claims)
+	addCounts(p.Proven, other.Proven)
+	addCounts(p.Bluffs, other.Bluffs)
+	addCounts(p.ChallengeChances, other.ChallengeChances)
+	addCounts(p.Challenges, other.Challenges)
+	addCounts(p.BlockChances, other.BlockChances)
+	addCounts(p.Blocks, other.Blocks)
+}
+
+func addCounts[K comparable](into, from map[K]int) {
+	for k, n := range from {
+		into[k] += n
+	}
+}
+
+// ProfileBook holds what has been seen of players over earlier games, keyed
+// by name, as seats change from one game to the next. Its profiles have
+// Player set to -1.
+type ProfileBook map[string]*Profile
+
+// Remember adds each player's profile from a finished game to the book.
+func (b ProfileBook) Remember(players []*Player, history []Event) {
+	for i, profile := range BuildProfiles(len(players), history) {
+		name := players[i].Name
+		past := NewProfile(-1)
+		if old := b[name]; old != nil {
+			// A profile read back from a file can be missing the maps that
+			// were empty when it was written.
+			past.Add(old)
+		}
+		past.Add(profile)
+		b[name] = past
+	}
+}
+
+func smoothedRate(hits, trials int, prior float64) float64 {
+	return (float64(hits) + prior*priorWeight) / (float64(trials) + priorWeight)
+}
+
+// BluffRate estimates how likely a claim of the given card is to be a bluff,
+// based on the claims that have been put to the test so far.
+func (p *Profile) BluffRate(c Card) float64 {
+	return smoothedRate(p.Bluffs[c], p.Bluffs[c]+p.Proven[c], defaultBluffRate)
+}
+
+// ChallengeRate estimates how likely the player is to challenge a claim of
+// the given card when they have the chance.
+func (p *Profile) ChallengeRate(c Card) float64 {
+	return smoothedRate(p.Challenges[c], p.ChallengeChances[c], defaultChallengeRate)
+}
+
+// BlockRate estimates how likely the player is to block the given action
+// when it is aimed at them.
+func (p *Profile) BlockRate(a Action) float64 {
+	return smoothedRate(p.Blocks[a], p.BlockChances[a], defaultBlockRate)
+}
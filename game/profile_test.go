@@ -0,0 +1,100 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestHistoryRecordsBluffedTax(t *testing.T) {
+	testCon := setupTestController()
+	testCon.AllPlayers[0].CardsHeld = []Card{Contessa, Captain}
+	testCon.UpdateGame(NewInputData(7, 0))
+	testCon.UpdateGame(NewInputData(1, 2))
+	testCon.UpdateGame(NewInputData(0, 0))
+	want := []Event{
+		{Turn: 0, Kind: Declared, Player: 0, Target: -1, Action: Tax, Card: Duke},
+		{Turn: 0, Kind: Challenged, Player: 2, Target: 0, Action: Tax, Card: Duke},
+		{Turn: 0, Kind: Revealed, Player: 0, Target: -1, Action: Tax, Card: Duke, Shown: Contessa},
+		{Turn: 0, Kind: Lost, Player: 0, Target: -1, Action: Tax, Card: Contessa},
+	}
+	history := testCon.History()
+	assertEqual[int](t, len(history), len(want), "history length")
+	for i := range min(len(history), len(want)) {
+		assertEqual[Event](t, history[i], want[i], fmt.Sprintf("event %d", i))
+	}
+}
+
+func TestHistoryRecordsPasses(t *testing.T) {
+	testCon := setupTestController()
+	testCon.State = State{MakeChallenge, Exchange}
+	testCon.setActivePlayers()
+	testCon.UpdateGame(NewInputData(0, 0))
+	history := testCon.History()
+	assertEqual[int](t, len(history), 4, "one pass per opponent")
+	for _, e := range history {
+		assertEqual[EventKind](t, e.Kind, Passed, "pass kind")
+		assertEqual[Card](t, e.Card, Ambassador, "pass card")
+		assertEqual[int](t, e.Target, 0, "pass target")
+	}
+}
+
+func TestBuildProfiles(t *testing.T) {
+	history := []Event{
+		{Kind: Declared, Player: 1, Target: -1, Action: Tax, Card: Duke},
+		{Kind: Challenged, Player: 2, Target: 1, Action: Tax, Card: Duke},
+		{Kind: Revealed, Player: 1, Target: -1, Action: Tax, Card: Duke, Shown: Captain},
+		{Kind: Declared, Player: 1, Target: -1, Action: Tax, Card: Duke},
+		{Kind: Passed, Player: 2, Target: 1, Action: Tax, Card: Duke},
+		{Kind: Declared, Player: 0, Target: 2, Action: Steal, Card: Captain},
+		{Kind: Allowed, Player: 2, Target: 0, Action: Steal},
+	}
+	profiles := BuildProfiles(3, history)
+	liar, challenger := profiles[1], profiles[2]
+	assertEqual[int](t, liar.Claims[Duke], 2, "duke claims")
+	assertEqual[int](t, liar.Bluffs[Duke], 1, "duke bluffs")
+	assertEqual[int](t, challenger.Challenges[Duke], 1, "duke challenges")
+	assertEqual[int](t, challenger.ChallengeChances[Duke], 2, "duke challenge chances")
+	assertEqual[int](t, challenger.BlockChances[Steal], 1, "steal block chances")
+	if liar.BluffRate(Duke) <= profiles[0].BluffRate(Duke) {
+		t.Errorf("caught bluffer should have a higher bluff rate: got %v, default %v",
+			liar.BluffRate(Duke), profiles[0].BluffRate(Duke))
+	}
+	if challenger.BlockRate(Steal) >= profiles[0].BlockRate(Steal) {
+		t.Errorf("player who allowed a steal should have a lower block rate: got %v, default %v",
+			challenger.BlockRate(Steal), profiles[0].BlockRate(Steal))
+	}
+}
+
+func TestProfileBookRemembersByName(t *testing.T) {
+	bluff := []Event{
+		{Kind: Declared, Player: 1, Target: -1, Action: Tax, Card: Duke},
+		{Kind: Challenged, Player: 0, Target: 1, Action: Tax, Card: Duke},
+		{Kind: Revealed, Player: 1, Target: -1, Action: Tax, Card: Duke, Shown: Captain},
+	}
+	first := setupTestController().AllPlayers[:2]
+	book := ProfileBook{}
+	book.Remember(first, bluff)
+
+	// A book read back from a file, then a game where the liar has moved
+	// seats.
+	data, err := json.Marshal(book)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	book = ProfileBook{}
+	if err := json.Unmarshal(data, &book); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	second := []*Player{first[1], first[0]}
+	book.Remember(second, []Event{
+		{Kind: Declared, Player: 0, Target: -1, Action: Tax, Card: Duke},
+		{Kind: Passed, Player: 1, Target: 0, Action: Tax, Card: Duke},
+	})
+
+	liar, challenger := book[first[1].Name], book[first[0].Name]
+	assertEqual[int](t, liar.Claims[Duke], 2, "duke claims over both games")
+	assertEqual[int](t, liar.Bluffs[Duke], 1, "duke bluffs over both games")
+	assertEqual[int](t, challenger.Challenges[Duke], 1, "duke challenges over both games")
+	assertEqual[int](t, challenger.ChallengeChances[Duke], 2, "duke challenge chances over both games")
+}
@@ -15,18 +15,23 @@ func NewInputData(sel, pIdx int) *InputData {
 
 type StateData struct {
 	ActivePlayers, ValidTargets []*Player
+	Current, Blocker            *Player
 	Target						*Player
 	BlockType                   Card
 	State                       State
+	History                     []Event
 }
 
 func NewStateData(c *Controller, valid []*Player) *StateData {
 	data := StateData{
 		ActivePlayers: c.activePlayers,
 		ValidTargets:  valid,
+		Current:       c.current,
+		Blocker:       c.blocker,
 		Target:		   c.target,
 		BlockType:     c.blockType,
 		State:         c.State,
+		History:       c.History(),
 	}
 	return &data
 }
@@ -39,6 +44,10 @@ type DisplayData struct {
 	ActionLog     *ActionLog
 	State         State
 	Selection	  int
+	CardCounts    []CardCount
+	History       []Event
+	Spectating    bool
+	Revealed      bool
 }
 
 func (c *Controller) NewDisplayData(validTargets []*Player) *DisplayData {
@@ -50,6 +59,14 @@ func (c *Controller) NewDisplayData(validTargets []*Player) *DisplayData {
 		ActionLog:     c.actionLog,
 		State:         c.State,
 		Selection:	   c.selection,
+		History:       c.History(),
+	}
+	for _, p := range c.AllPlayers {
+		if !p.IsLocal {
+			continue
+		}
+		data.CardCounts = c.View(p.Index).CardCounts()
+		break
 	}
 	return &data
 }
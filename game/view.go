@@ -0,0 +1,237 @@
+package game
+
+import "slices"
+
+// PlayerView is what one seat is allowed to know about a player. CardsHeld is
+// only filled in for the seat's own player; everyone else just has a HandSize.
+type PlayerView struct {
+	Name      string
+	Index     int
+	Coins     int
+	HandSize  int
+	CardsHeld []Card
+	CardsLost []Card
+	IsHuman   bool
+}
+
+// View is the redacted state of the game from the point of view of a single
+// seat. It holds nothing that the player in that seat couldn't work out by
+// watching the table, so it is safe to hand to advisors or send over the wire.
+//
+// Current, Target, Blocker and Challenger are player indices, or -1 if there
+// is no such player this turn. Returned is only filled in while the seat is
+// the one part way through an Exchange.
+//
+// A spectator's view has Seat set to Spectator. Revealed is set if it shows
+// every player's hand, which is only safe to hand out once it is too late to
+// be of use to anyone still playing.
+type View struct {
+	State
+	// Move is the Controller's Moves when the view was taken.
+	Move       int
+	Seat       int
+	Revealed   bool
+	Players    []PlayerView
+	Active     []int
+	Current    int
+	Target     int
+	Blocker    int
+	Challenger int
+	BlockType  Card
+	Returned   []Card
+	DeckSize   int
+	// Deck is how many cards have left or rejoined the deck so far, which
+	// places the view among the Events of the game's ShuffleRecord.
+	Deck    int
+	History []Event
+	Log     []string
+	// Commitment is the hash of the seed the deck was shuffled from, and
+	// Record reveals the seed once the game is over. See ShuffleRecord.
+	Commitment string
+	Record     *ShuffleRecord
+}
+
+// Spectator is the seat of someone watching the game rather than playing it.
+const Spectator = -1
+
+func indexOf(p *Player) int {
+	if p == nil {
+		return -1
+	}
+	return p.Index
+}
+
+// View builds the redacted view of the game for the player at index seat.
+// Everything in it is copied, so it can be read safely once the controller
+// has moved on.
+func (c *Controller) View(seat int) *View {
+	view := View{
+		Seat:       seat,
+		Current:    indexOf(c.current),
+		Target:     indexOf(c.target),
+		Blocker:    indexOf(c.blocker),
+		Challenger: indexOf(c.challenger),
+		BlockType:  c.blockType,
+		State:      c.State,
+		Move:       c.moves,
+		DeckSize:   c.deckSize(),
+		Deck:       len(c.deckLog),
+		History:    c.History(),
+		Log:        slices.Clone(c.actionLog.Items),
+		Commitment: c.Commitment(),
+	}
+	if c.Phase == EndGame {
+		view.Record = c.ShuffleRecord()
+	}
+	for _, p := range c.AllPlayers {
+		pv := PlayerView{
+			Name:      p.Name,
+			Index:     p.Index,
+			Coins:     p.Coins,
+			HandSize:  len(p.CardsHeld),
+			CardsLost: slices.Clone(p.CardsLost),
+			IsHuman:   p.IsHuman,
+		}
+		if p.Index == seat {
+			pv.CardsHeld = slices.Clone(p.CardsHeld)
+		}
+		view.Players = append(view.Players, pv)
+	}
+	for _, p := range c.activePlayers {
+		view.Active = append(view.Active, p.Index)
+	}
+	if view.Current == seat {
+		view.Returned = slices.Clone(c.returnedCards)
+	}
+	return &view
+}
+
+// RevealedView is the view of a spectator who is allowed to see everyone's
+// hand, and the cards on their way back to the deck during an Exchange.
+func (c *Controller) RevealedView() *View {
+	view := c.View(Spectator)
+	view.Revealed = true
+	for i, p := range c.AllPlayers {
+		view.Players[i].CardsHeld = slices.Clone(p.CardsHeld)
+	}
+	view.Returned = slices.Clone(c.returnedCards)
+	return view
+}
+
+// IsActive reports whether the seat is expected to respond in the current
+// phase.
+func (v *View) IsActive() bool {
+	return slices.Contains(v.Active, v.Seat)
+}
+
+// Unseen returns every card the seat can't account for, whether it is in the
+// deck or in an opponent's hand.
+func (v *View) Unseen() []Card {
+	var unseen []Card
+	for _, card := range AllCards {
+		for range 3 {
+			unseen = append(unseen, card)
+		}
+	}
+	seen := slices.Clone(v.Returned)
+	for _, p := range v.Players {
+		seen = append(seen, p.CardsHeld...)
+		seen = append(seen, p.CardsLost...)
+	}
+	for _, card := range seen {
+		if idx := slices.Index(unseen, card); idx >= 0 {
+			unseen = slices.Delete(unseen, idx, idx+1)
+		}
+	}
+	return unseen
+}
+
+// CardCount is how many copies of a card the seat can't account for, and the
+// chance that each opponent holds at least one of them. Odds is keyed by
+// player index and only covers opponents still in the game.
+type CardCount struct {
+	Card   Card
+	Unseen int
+	Odds   map[int]float64
+}
+
+// CardCounts works out a CardCount for every role from the cards the seat can
+// see: its own hand, everything lost so far and what it has returned.
+func (v *View) CardCounts() []CardCount {
+	unseen := v.Unseen()
+	pool := len(unseen)
+	var counts []CardCount
+	for _, card := range AllCards {
+		count := CardCount{Card: card, Odds: map[int]float64{}}
+		for _, c := range unseen {
+			if c == card {
+				count.Unseen++
+			}
+		}
+		for _, p := range v.Players {
+			if p.Index == v.Seat || len(p.CardsLost) == 2 {
+				continue
+			}
+			count.Odds[p.Index] = 1 - chanceOfNone(pool, count.Unseen, p.HandSize)
+		}
+		counts = append(counts, count)
+	}
+	return counts
+}
+
+// chanceOfNone is the chance that a hand of size drawn from a pool of cards
+// contains none of the copies, from the hypergeometric distribution.
+func chanceOfNone(pool, copies, size int) float64 {
+	chance := 1.0
+	for i := range size {
+		if pool-copies-i <= 0 {
+			return 0
+		}
+		chance *= float64(pool-copies-i) / float64(pool-i)
+	}
+	return chance
+}
+
+// DisplayData rebuilds the data a Display needs from the view, so a remote
+// player's screen can be drawn exactly like a local one. Only the seat's own
+// player is marked as local, and everyone else's hand is left empty.
+func (v *View) DisplayData() *DisplayData {
+	var players []*Player
+	for _, pv := range v.Players {
+		p := Player{
+			Name:      pv.Name,
+			Index:     pv.Index,
+			Coins:     pv.Coins,
+			CardsHeld: slices.Clone(pv.CardsHeld),
+			CardsLost: slices.Clone(pv.CardsLost),
+			IsHuman:   pv.IsHuman,
+			IsLocal:   pv.Index == v.Seat,
+		}
+		players = append(players, &p)
+	}
+	data := DisplayData{
+		AllPlayers: players,
+		ActionLog:  &ActionLog{Items: slices.Clone(v.Log), Length: len(v.Log)},
+		State:      v.State,
+		History:    slices.Clone(v.History),
+		Spectating: v.Seat == Spectator,
+		Revealed:   v.Revealed,
+	}
+	if !v.Revealed {
+		data.CardCounts = v.CardCounts()
+	}
+	if v.Current >= 0 {
+		data.Current = players[v.Current]
+	}
+	for _, idx := range v.Active {
+		data.ActivePlayers = append(data.ActivePlayers, players[idx])
+	}
+	if v.Phase == SelectTarget {
+		for _, p := range players {
+			if p.Index != v.Current && p.IsAlive() {
+				data.ValidTargets = append(data.ValidTargets, p)
+			}
+		}
+	}
+	return &data
+}
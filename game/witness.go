@@ -0,0 +1,134 @@
+package game
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Witness keeps track of the cards one seat saw over a game, so that the
+// ShuffleRecord handed out at the end can be checked against them. Checking
+// a record against its seed only shows that the record is honest; a host
+// could still have dealt stacked cards and published a clean record after.
+type Witness struct {
+	sightings []sighting
+	history   int
+}
+
+// sighting is what one view showed of the cards once deck events of the
+// record had happened. hands holds every card that reached each player whose
+// hand could be seen, whether held, lost or on its way back to the deck.
+type sighting struct {
+	deck  int
+	hands map[int][]Card
+	lost  [][]Card
+	shown []Event
+}
+
+// See notes down the cards in a view. Views may be skipped, as when a
+// connection drops, but must be seen in the order they were taken.
+func (w *Witness) See(v *View) {
+	if v.Commitment == "" {
+		// The deck was kept by a Dealer, so there will be no record.
+		return
+	}
+	s := sighting{deck: v.Deck, hands: map[int][]Card{}}
+	for _, p := range v.Players {
+		s.lost = append(s.lost, slices.Clone(p.CardsLost))
+		if !v.Revealed && p.Index != v.Seat {
+			continue
+		}
+		hand := append(slices.Clone(p.CardsHeld), p.CardsLost...)
+		if p.Index == v.Current {
+			hand = append(hand, v.Returned...)
+		}
+		s.hands[p.Index] = hand
+	}
+	for _, e := range v.History[min(w.history, len(v.History)):] {
+		if e.Kind == Revealed && e.Proven {
+			s.shown = append(s.shown, e)
+		}
+	}
+	w.history = len(v.History)
+	w.sightings = append(w.sightings, s)
+}
+
+// Check replays the record's draws and returns up to each view seen, and
+// makes sure they agree with it: the hands it could see hold exactly the
+// cards dealt to them, every lost card was dealt to the player who lost it,
+// and every card shown to win a challenge went back to the deck.
+func (w *Witness) Check(r *ShuffleRecord) error {
+	hands := make([][]Card, r.Players)
+	next := 0
+	for _, s := range w.sightings {
+		if s.deck > len(r.Events) {
+			return fmt.Errorf("the record stops after %d cards, but %d had been dealt", len(r.Events), s.deck)
+		}
+		var returned []DeckEvent
+		for _, event := range r.Events[next:s.deck] {
+			if event.Player < 0 || event.Player >= r.Players {
+				return fmt.Errorf("the record deals to player %d, who isn't playing", event.Player+1)
+			}
+			if !event.Returned {
+				hands[event.Player] = append(hands[event.Player], event.Card)
+				continue
+			}
+			returned = append(returned, event)
+			if i := slices.Index(hands[event.Player], event.Card); i >= 0 {
+				hands[event.Player] = slices.Delete(hands[event.Player], i, i+1)
+			}
+		}
+		next = s.deck
+		for player, hand := range s.hands {
+			if player < 0 || player >= r.Players {
+				return fmt.Errorf("there is no player %d in the record", player+1)
+			}
+			if !sameCards(hand, hands[player]) {
+				return fmt.Errorf("player %d was dealt %s, but the record deals them %s", player+1, cardNames(hand), cardNames(hands[player]))
+			}
+		}
+		for player, lost := range s.lost {
+			if player >= r.Players {
+				return fmt.Errorf("there is no player %d in the record", player+1)
+			}
+			if !holdsAll(hands[player], lost) {
+				return fmt.Errorf("player %d lost %s, which the record never dealt them", player+1, cardNames(lost))
+			}
+		}
+		for _, e := range s.shown {
+			back := DeckEvent{Player: e.Player, Card: e.Shown, Returned: true}
+			if !slices.Contains(returned, back) {
+				return fmt.Errorf("player %d showed %s, which the record doesn't have them return", e.Player+1, e.Shown.Name())
+			}
+		}
+	}
+	return nil
+}
+
+func sameCards(a, b []Card) bool {
+	return len(a) == len(b) && holdsAll(a, b)
+}
+
+// holdsAll reports whether every card in want is in hand, counting repeats.
+func holdsAll(hand, want []Card) bool {
+	left := slices.Clone(hand)
+	for _, card := range want {
+		i := slices.Index(left, card)
+		if i < 0 {
+			return false
+		}
+		left = slices.Delete(left, i, i+1)
+	}
+	return true
+}
+
+func cardNames(cards []Card) string {
+	if len(cards) == 0 {
+		return "nothing"
+	}
+	names := make([]string, len(cards))
+	for i, card := range cards {
+		names[i] = card.Name()
+	}
+	return strings.Join(names, ", ")
+}
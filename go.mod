@@ -3,6 +3,8 @@ module kugo
 go 1.24.6
 
 require (
-	golang.org/x/sys v0.35.0 // indirect
-	golang.org/x/term v0.34.0 // indirect
+	golang.org/x/crypto v0.41.0
+	golang.org/x/term v0.34.0
 )
+
+require golang.org/x/sys v0.35.0 // indirect
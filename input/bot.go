@@ -0,0 +1,211 @@
+package input
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"kugo/game"
+)
+
+// actionCosts is how many coins an action needs before a bot will pick it.
+var actionCosts = map[game.Action]int{
+	game.Coup:        7,
+	game.Assassinate: 3,
+}
+
+// profileOf returns the model of the given player built from everything they
+// have done in public so far this game, and in earlier games if there is a
+// record of them.
+func (st *botState) profileOf(p *game.Player) *game.Profile {
+	return st.profiles()[p.Index]
+}
+
+// profiles is profileOf for every player, by index.
+func (st *botState) profiles() []*game.Profile {
+	profiles := game.BuildProfiles(len(st.allPlayers), st.history)
+	for i, p := range st.allPlayers {
+		if past := st.past[p.Name]; past != nil {
+			profiles[i].Add(past)
+		}
+	}
+	return profiles
+}
+
+// explain records why a bot made a decision, both for the bot thoughts panel
+// and for debug.log. Anyone can open the panel, so a reason must only say
+// what the whole table can see, and never what is in the bot's hand.
+func (ih *InputHandler) explain(pIdx int, reason string) {
+	entry := fmt.Sprintf("%s: %s", ih.allPlayers[pIdx], reason)
+	ih.thoughtsMu.Lock()
+	ih.thoughts.Enqueue(entry)
+	ih.thoughtsMu.Unlock()
+	ih.notify()
+	game.Debugf("bot %s", entry)
+}
+
+// BotThoughts returns the most recent bot rationales, or nil if the local
+// player has the panel switched off.
+func (ih *InputHandler) BotThoughts() []string {
+	if !ih.showThoughts.Load() {
+		return nil
+	}
+	ih.thoughtsMu.Lock()
+	defer ih.thoughtsMu.Unlock()
+	return append([]string{}, ih.thoughts.Items...)
+}
+
+// countLost returns how many copies of a card have been lost by anyone.
+func (st *botState) countLost(card game.Card) int {
+	var n int
+	for _, p := range st.allPlayers {
+		for _, c := range p.CardsLost {
+			if c == card {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// decideAction picks an affordable action at random. The bot isn't picky about
+// whether it holds the card for a claim, and doesn't say whether it does.
+func (st *botState) decideAction(pIdx int, rng *rand.Rand) (int, string) {
+	bot := st.allPlayers[pIdx]
+	if bot.Coins >= 10 {
+		return int(game.Coup), "10+ coins, so a Coup is forced"
+	}
+	var options []game.Action
+	for a := game.Income; a <= game.Tax; a++ {
+		if bot.Coins < actionCosts[a] {
+			continue
+		}
+		options = append(options, a)
+	}
+	action := options[rng.IntN(len(options))]
+	card := action.Card()
+	if card == game.NoCard {
+		return int(action), fmt.Sprintf("taking %s; no claim needed", action)
+	}
+	return int(action), fmt.Sprintf("%d coins; claiming %s for %s", bot.Coins, card, action)
+}
+
+// decideChallenge rolls whether the bot challenges the claim currently on the
+// table. Players who have been caught bluffing that card before are challenged
+// more often, as are claims of cards the bot can see are mostly accounted for.
+// Its reasons leave out the copies in its own hand, and with them the odds.
+func (st *botState) decideChallenge(pIdx int, rng *rand.Rand) (bool, string) {
+	claimant, card := st.current, st.action.Card()
+	if st.phase == game.ChallengeBlock {
+		claimant, card = st.blocker, st.blockType
+	}
+	if claimant == nil {
+		return rng.IntN(100) < 20, "no read on this claim"
+	}
+	profile := st.profileOf(claimant)
+	lost := st.countLost(card)
+	held := 0
+	for _, c := range st.allPlayers[pIdx].CardsHeld {
+		if c == card {
+			held++
+		}
+	}
+
+	var reasons []string
+	if n := profile.Claims[card]; n > 1 {
+		reasons = append(reasons, fmt.Sprintf("%s has claimed %s %d times", claimant, card, n))
+	}
+	if n := profile.Bluffs[card]; n > 0 {
+		reasons = append(reasons, fmt.Sprintf("caught bluffing it %d times", n))
+	}
+	if lost > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d already lost", lost))
+	}
+
+	rate := int(100*profile.BluffRate(card)) + 15*(lost+held)
+	if lost+held >= 3 {
+		rate = 100
+	}
+	rate = min(rate, 100)
+	challenge := rng.IntN(100) < rate
+	verdict := "letting it go"
+	if challenge {
+		verdict = "challenging"
+	}
+	reasons = append(reasons, verdict)
+	return challenge, strings.Join(reasons, "; ")
+}
+
+// decideBlock returns the block menu selection for the bot, always blocking
+// with a card it holds. Otherwise it may bluff, and is warier of bluffing
+// against players who are quick to challenge that card. A block reads the
+// same whether it is a bluff or not.
+func (st *botState) decideBlock(pIdx int, rng *rand.Rand) (int, string) {
+	hand := st.allPlayers[pIdx].CardsHeld
+	cards := st.action.Blockers()
+	blocking := func(n int) (int, string) {
+		return n, fmt.Sprintf("claiming %s to block %s's %s", cards[n-1], st.current, st.action)
+	}
+	for i, card := range cards {
+		if slices.Contains(hand, card) {
+			return blocking(i + 1)
+		}
+	}
+	n := rng.IntN(len(cards)) + 1
+	card := cards[n-1]
+	rate := 20
+	if st.current != nil {
+		rate = int(25 * (1 - st.profileOf(st.current).ChallengeRate(card)))
+	}
+	if rng.IntN(100) < rate {
+		return blocking(n)
+	}
+	return 0, fmt.Sprintf("letting %s's %s through", st.current, st.action)
+}
+
+// pickTarget chooses a target for the current bot and returns it as the
+// 1-based selection expected by selectTarget. Steal and Assassinate are aimed
+// at whoever has been least willing to block them, with ties broken at random.
+// Coup can't be blocked, so any target will do.
+func (st *botState) pickTarget(rng *rand.Rand) (int, string) {
+	targets := st.validTargets
+	offset := rng.IntN(len(targets))
+	if st.action != game.Steal && st.action != game.Assassinate {
+		return offset + 1, fmt.Sprintf("%s can't be blocked; picking %s", st.action, targets[offset])
+	}
+	profiles := st.profiles()
+	best, bestRate := offset, 2.0
+	for i := range targets {
+		idx := (offset + i) % len(targets)
+		p := targets[idx]
+		if st.action == game.Steal && p.Coins == 0 {
+			continue
+		}
+		rate := profiles[p.Index].BlockRate(st.action)
+		if rate < bestRate {
+			best, bestRate = idx, rate
+		}
+	}
+	if bestRate > 1 {
+		return best + 1, fmt.Sprintf("nobody has coins to steal; picking %s", targets[best])
+	}
+	reason := fmt.Sprintf(
+		"%s blocks %s least often (%.0f%%); targeting them",
+		targets[best],
+		st.action,
+		100*bestRate,
+	)
+	return best + 1, reason
+}
+
+// pickReveal returns the 1-based card selection for a reveal, showing the
+// claimed card if the bot has it and a random card otherwise. Which it is
+// comes out when the card is turned over, not before.
+func (st *botState) pickReveal(hand []game.Card, claimed game.Card, rng *rand.Rand) (int, string) {
+	reason := fmt.Sprintf("challenged on %s; turning a card over", claimed)
+	if idx := slices.Index(hand, claimed); idx >= 0 {
+		return idx + 1, reason
+	}
+	return rng.IntN(len(hand)) + 1, reason
+}
@@ -0,0 +1,118 @@
+package input
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"kugo/game"
+)
+
+// testBotState is a three player game at the given phase, with player 1 having
+// just claimed action and the bot in seat 0 holding hand.
+func testBotState(t *testing.T, phase game.Phase, action game.Action, hand []game.Card) *botState {
+	t.Helper()
+	var players []*game.Player
+	for i, name := range []string{"Ann", "Ben", "Cat"} {
+		p, err := game.NewPlayer(name, i, false, false)
+		if err != nil {
+			t.Fatalf("NewPlayer: %v", err)
+		}
+		p.Coins = 4
+		p.CardsHeld = []game.Card{game.Ambassador, game.Assassin}
+		players = append(players, p)
+	}
+	players[0].CardsHeld = hand
+	return newBotState(players, &game.StateData{
+		ActivePlayers: players[:1],
+		Current:       players[1],
+		State:         game.State{Phase: phase, Action: action},
+	})
+}
+
+func TestBotReasonsHideHand(t *testing.T) {
+	tests := []struct {
+		name      string
+		phase     game.Phase
+		action    game.Action
+		with      []game.Card
+		without   []game.Card
+		reasoning func(st *botState, rng *rand.Rand) string
+	}{
+		{
+			name:    "action",
+			phase:   game.SelectAction,
+			with:    []game.Card{game.Duke, game.Captain},
+			without: []game.Card{game.Contessa, game.Ambassador},
+			reasoning: func(st *botState, rng *rand.Rand) string {
+				_, reason := st.decideAction(0, rng)
+				return reason
+			},
+		},
+		{
+			name:    "challenge",
+			phase:   game.MakeChallenge,
+			action:  game.Tax,
+			with:    []game.Card{game.Duke, game.Duke},
+			without: []game.Card{game.Contessa, game.Captain},
+			reasoning: func(st *botState, rng *rand.Rand) string {
+				_, reason := st.decideChallenge(0, rng)
+				return reason
+			},
+		},
+		{
+			name:    "block",
+			phase:   game.MakeBlock,
+			action:  game.Steal,
+			with:    []game.Card{game.Captain, game.Duke},
+			without: []game.Card{game.Contessa, game.Duke},
+			reasoning: func(st *botState, rng *rand.Rand) string {
+				_, reason := st.decideBlock(0, rng)
+				return reason
+			},
+		},
+		{
+			name:    "reveal",
+			phase:   game.ChallengeReveal,
+			action:  game.Tax,
+			with:    []game.Card{game.Contessa, game.Duke},
+			without: []game.Card{game.Contessa, game.Captain},
+			reasoning: func(st *botState, rng *rand.Rand) string {
+				_, reason := st.pickReveal(st.allPlayers[0].CardsHeld, game.Duke, rng)
+				return reason
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Every reason given with the card in hand must also be one
+			// that could be given without it.
+			reasons := func(hand []game.Card) map[string]bool {
+				st := testBotState(t, tc.phase, tc.action, hand)
+				seen := map[string]bool{}
+				for seed := range uint64(200) {
+					seen[tc.reasoning(st, rand.New(rand.NewPCG(seed, seed)))] = true
+				}
+				return seen
+			}
+			without := reasons(tc.without)
+			for reason := range reasons(tc.with) {
+				if !without[reason] {
+					t.Errorf("%q is only given holding %v", reason, tc.with)
+				}
+			}
+		})
+	}
+}
+
+func TestBotsRememberEarlierGames(t *testing.T) {
+	st := testBotState(t, game.MakeChallenge, game.Tax, []game.Card{game.Contessa, game.Captain})
+	past := game.NewProfile(-1)
+	past.Bluffs[game.Duke] = 3
+	st.past = game.ProfileBook{"Ben": past}
+	if got := st.profileOf(st.allPlayers[1]).Bluffs[game.Duke]; got != 3 {
+		t.Errorf("Ben has %d Duke bluffs on record, want the 3 from earlier games", got)
+	}
+	if got := st.profileOf(st.allPlayers[2]).Bluffs[game.Duke]; got != 0 {
+		t.Errorf("Cat has %d Duke bluffs on record, want none", got)
+	}
+}
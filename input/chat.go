@@ -0,0 +1,70 @@
+package input
+
+import "unicode"
+
+// Emotes are the canned lines a player can send without typing, mostly for
+// calling out bluffs.
+var Emotes = []string{
+	"I don't believe you.",
+	"Nice bluff.",
+	"Sure you have that Duke.",
+	"Bold move.",
+	"You'll regret that.",
+	"Never in doubt.",
+	"Well played.",
+	"Good game!",
+}
+
+// MaxChatLength is the most runes a chat line can hold.
+const MaxChatLength = 200
+
+// ChatEntry turns key presses into chat lines. Outside of it, number keys
+// are moves; 'c' starts typing a line and 'e' opens the emote menu, and
+// either takes over the keys until a line is sent or the entry is cancelled.
+type ChatEntry struct {
+	Typing  bool
+	Emoting bool
+	text    []rune
+}
+
+// Press handles a key. It reports whether the key was meant for the chat
+// rather than the game, along with a line to send if one is finished.
+func (ce *ChatEntry) Press(key rune) (line string, handled bool) {
+	switch {
+	case ce.Typing:
+		switch key {
+		case '\r', '\n':
+			line = string(ce.text)
+			ce.Typing, ce.text = false, nil
+		case 27: // Escape
+			ce.Typing, ce.text = false, nil
+		case 127, '\b':
+			if len(ce.text) > 0 {
+				ce.text = ce.text[:len(ce.text)-1]
+			}
+		default:
+			if unicode.IsPrint(key) && len(ce.text) < MaxChatLength {
+				ce.text = append(ce.text, key)
+			}
+		}
+		return line, true
+	case ce.Emoting:
+		ce.Emoting = false
+		if idx := int(key - '1'); idx >= 0 && idx < len(Emotes) {
+			line = Emotes[idx]
+		}
+		return line, true
+	case key == 'c':
+		ce.Typing = true
+		return "", true
+	case key == 'e':
+		ce.Emoting = true
+		return "", true
+	}
+	return "", false
+}
+
+// Text is the line typed so far.
+func (ce *ChatEntry) Text() string {
+	return string(ce.text)
+}
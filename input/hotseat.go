@@ -0,0 +1,137 @@
+package input
+
+import (
+	"context"
+	"sync"
+
+	"kugo/game"
+)
+
+// HotSeat shares one keyboard between several human players. Keys go to the
+// player holding the terminal, and it only changes hands once the next player
+// to act has pressed Enter, so the screen can be blanked in between and no
+// one sees a hand that isn't theirs.
+//
+// When more than one human can answer at once, as in a challenge window, they
+// are asked one at a time in turn order from the acting player, skipping
+// anyone who has already answered.
+type HotSeat struct {
+	mu      sync.Mutex
+	holder  int
+	waiting int
+	// accepting is whether the holder is the player the game is waiting on.
+	// Other keys are thrown away so nobody can answer twice.
+	accepting bool
+	changed   chan struct{}
+}
+
+// maxSeats is the most players a game can have.
+const maxSeats = 6
+
+// NewHotSeat hands the terminal to first, once they press Enter.
+func NewHotSeat(first int) *HotSeat {
+	return &HotSeat{holder: -1, waiting: first, changed: make(chan struct{}, 1)}
+}
+
+// Changes tells the game loop the terminal has been taken, so whoever took it
+// is shown their hand.
+func (hs *HotSeat) Changes() <-chan struct{} {
+	return hs.changed
+}
+
+// Update works out which human the game is waiting on from the current state,
+// and asks for the terminal to be passed to them if they don't have it. It
+// is cheap enough to call whenever the game loop comes round, which it needs
+// to be as players answer challenges without the state changing.
+func (hs *HotSeat) Update(data *game.StateData) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	next := -1
+	if data.State.Phase != game.EndGame {
+		next = nextHuman(data.ActivePlayers, data.Current)
+	}
+	hs.accepting = next >= 0 && next == hs.holder
+	switch {
+	case next >= 0 && next != hs.holder:
+		hs.waiting = next
+	case hs.holder >= 0:
+		// Nobody else needs the terminal, so leave it where it is.
+		hs.waiting = -1
+	}
+}
+
+// nextHuman picks the first human who still has to answer, counting round the
+// table from the player after current.
+func nextHuman(active []*game.Player, current *game.Player) int {
+	start := 0
+	if current != nil {
+		start = current.Index + 1
+	}
+	next, nextDist := -1, 0
+	for _, p := range active {
+		if !p.IsHuman || p.Responded {
+			continue
+		}
+		dist := (p.Index - start + maxSeats) % maxSeats
+		if next < 0 || dist < nextDist {
+			next, nextDist = p.Index, dist
+		}
+	}
+	return next
+}
+
+// Holder is the seat of the player with the terminal, or -1 before anyone
+// has taken it.
+func (hs *HotSeat) Holder() int {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	return hs.holder
+}
+
+// Waiting is the seat the terminal is being passed to, or -1 if it isn't
+// being passed.
+func (hs *HotSeat) Waiting() int {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	return hs.waiting
+}
+
+// Route passes keys on to the channel of whoever holds the terminal until ctx
+// is done. While the terminal is being passed on, every key but Enter is
+// ignored.
+func (hs *HotSeat) Route(ctx context.Context, keys <-chan rune, chans [maxSeats]chan rune) {
+	for {
+		var key rune
+		select {
+		case key = <-keys:
+		case <-ctx.Done():
+			return
+		}
+		hs.mu.Lock()
+		out := chan rune(nil)
+		switch {
+		case hs.waiting >= 0:
+			if key == '\r' || key == '\n' {
+				// They were only asked to take it because the game is
+				// waiting on them.
+				hs.holder, hs.waiting = hs.waiting, -1
+				hs.accepting = true
+				select {
+				case hs.changed <- struct{}{}:
+				default:
+				}
+			}
+		case hs.accepting:
+			out = chans[hs.holder]
+		}
+		hs.mu.Unlock()
+		if out == nil {
+			continue
+		}
+		select {
+		case out <- key:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -5,8 +5,9 @@ import (
 	"fmt"
 	"kugo/game"
 	"math/rand/v2"
-	"os"
 	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -29,15 +30,23 @@ var inputSubHandlers = map[game.Phase]func(*InputHandler) *game.InputData{
 type InputHandler struct {
 	phase         game.Phase
 	action        game.Action
-	rng           *rand.Rand
 	allPlayers    []*game.Player
 	activePlayers []*game.Player
 	validTargets  []*game.Player
 	target        *game.Player
-	blockType     game.Card
+	bots          *botState
+	botsMu        sync.Mutex
+	past          game.ProfileBook
 	PlayerChans   [6]chan rune
 	chanErr       chan error
 	inputData     *game.InputData
+	thoughts      *game.ActionLog
+	thoughtsMu    sync.Mutex
+	showThoughts  atomic.Bool
+	hintRequested atomic.Bool
+	typing        atomic.Bool
+	changed       chan struct{}
+	menu          Navigator
 }
 
 // NewInputHandler is called during initialization to set up the InputHandler.
@@ -46,23 +55,89 @@ func NewInputHandler(players []*game.Player, chanErr chan error) *InputHandler {
 	for i := range len(players) {
 		PlayerChans[i] = make(chan rune)
 	}
-	rng := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
 	ih := InputHandler{
-		rng:         rng,
 		allPlayers:  players,
 		PlayerChans: PlayerChans,
 		chanErr:     chanErr,
+		thoughts:    game.NewActionLog(5),
+		changed:     make(chan struct{}, 1),
 	}
 	return &ih
 }
 
+// SetProfiles gives the bots what was seen of the players in earlier games,
+// to go on until this one tells them more. The book mustn't be changed once
+// it has been handed over.
+func (ih *InputHandler) SetProfiles(past game.ProfileBook) {
+	ih.past = past
+}
+
 func (ih *InputHandler) UpdateStateData(data *game.StateData) {
 	ih.activePlayers = data.ActivePlayers
 	ih.validTargets = data.ValidTargets
 	ih.target = data.Target
-	ih.blockType = data.BlockType
 	ih.phase = data.State.Phase
 	ih.action = data.State.Action
+
+	bots := newBotState(ih.allPlayers, data)
+	bots.past = ih.past
+	ih.botsMu.Lock()
+	ih.bots = bots
+	ih.botsMu.Unlock()
+}
+
+// botState is the game as the bots see it: a copy taken at the last
+// UpdateStateData, so that bots thinking in goroutines of their own never
+// read the game while the controller is changing it.
+type botState struct {
+	phase         game.Phase
+	action        game.Action
+	allPlayers    []*game.Player
+	activePlayers []*game.Player
+	validTargets  []*game.Player
+	current       *game.Player
+	blocker       *game.Player
+	blockType     game.Card
+	history       []game.Event
+	past          game.ProfileBook
+}
+
+func newBotState(players []*game.Player, data *game.StateData) *botState {
+	st := botState{
+		phase:     data.State.Phase,
+		action:    data.State.Action,
+		blockType: data.BlockType,
+		history:   slices.Clone(data.History),
+	}
+	for _, p := range players {
+		cp := *p
+		cp.CardsHeld = slices.Clone(p.CardsHeld)
+		cp.CardsLost = slices.Clone(p.CardsLost)
+		st.allPlayers = append(st.allPlayers, &cp)
+	}
+	// The same players, but the copies of them.
+	copied := func(p *game.Player) *game.Player {
+		if p == nil {
+			return nil
+		}
+		return st.allPlayers[p.Index]
+	}
+	for _, p := range data.ActivePlayers {
+		st.activePlayers = append(st.activePlayers, copied(p))
+	}
+	for _, p := range data.ValidTargets {
+		st.validTargets = append(st.validTargets, copied(p))
+	}
+	st.current = copied(data.Current)
+	st.blocker = copied(data.Blocker)
+	return &st
+}
+
+// botView is the latest botState.
+func (ih *InputHandler) botView() *botState {
+	ih.botsMu.Lock()
+	defer ih.botsMu.Unlock()
+	return ih.bots
 }
 
 // GetInputData is the core method of InputHandler. When called it deplyoys an
@@ -90,7 +165,6 @@ func (ih *InputHandler) GetInputData() *game.InputData {
 func (ih *InputHandler) clearData() {
 	ih.activePlayers = nil
 	ih.validTargets = nil
-	ih.blockType = game.NoCard
 }
 
 // getSignal is a useful helper function that makes up the core functionality
@@ -198,6 +272,7 @@ func (ih *InputHandler) flagAsResponded(pIdx int) {
 			continue
 		}
 		p.Responded = true
+		ih.notify()
 		return
 	}
 }
@@ -277,19 +352,20 @@ func (ih *InputHandler) endGame() *game.InputData {
 	return game.NewInputData(0, 0)
 }
 
-// CreateBotInputStream uses the input handler's random number generator to
+// CreateBotInputStream uses a random number generator of the bot's own to
 // produce semi-random behaviour from bots when they have no obvious choice
 // to make. If the bot has the required card for a reveal then they will
 // reveal it, and will always block if they have the required card. Otherwise
-// they choose actions randomly from the available options. They challenge at
-// a fixed percentage rate, given by challengeRate.
+// they choose actions randomly from the available options. How often they
+// challenge, bluff a block and who they pick on is shaped by the profiles
+// they build of their opponents from the game history (see bot.go).
 func (ih *InputHandler) CreateBotInputStream(ctx context.Context, outChan chan<- rune, pIdx int) {
 	defer ih.RecoverPanic("Panic captured by CreateBotInputStream")
+	rng := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
 	var retryCounter int
 	for {
 		var n int
-		var challengeRate = 20
-		var waitTime = 3000 + ih.rng.IntN(2000)
+		var waitTime = 3000 + rng.IntN(2000)
 		// wait for 3.0 - 5.0 seconds to allow humans to read the logs, but still
 		// keep the game flowing quickly. Randomness makes it feel more like the
 		// bot is thinking organically. Without this wait the response is immediate
@@ -299,24 +375,28 @@ func (ih *InputHandler) CreateBotInputStream(ctx context.Context, outChan chan<-
 			return
 		default:
 			// move to switch statement if player is alive, otherwise close the stream.
-			for _, p := range ih.allPlayers {
-				if p.Index != pIdx || p.IsAlive() {
-					continue
-				}
+			if st := ih.botView(); st != nil && !st.allPlayers[pIdx].IsAlive() {
 				return
 			}
 		}
 
 		time.Sleep(time.Duration(500) * time.Millisecond)
-		switch ih.phase {
+		st := ih.botView()
+		if st == nil {
+			continue
+		}
+		switch st.phase {
 		case game.SelectAction:
 			time.Sleep(time.Duration(2000) * time.Millisecond)
-			n = ih.rng.IntN(7) + 1
+			// Decide on the game as it is after the pause, not before it.
+			st = ih.botView()
+			n, reason := st.decideAction(pIdx, rng)
+			ih.explain(pIdx, reason)
 			outChan <- rune(n + '0')
 		case game.SelectTarget:
 			// No need for the bots to cancel their target selections, so need
 			// to add one to the final result.
-			if len(ih.validTargets) == 0 {
+			if len(st.validTargets) == 0 {
 				retryCounter++
 				time.Sleep(time.Duration(100) * time.Millisecond)
 				continue
@@ -325,49 +405,29 @@ func (ih *InputHandler) CreateBotInputStream(ctx context.Context, outChan chan<-
 				panic("Retried valid target check 10+ times")
 			}
 			retryCounter = 0
-			n = ih.rng.IntN(len(ih.validTargets)) + 1
+			n, reason := st.pickTarget(rng)
+			ih.explain(pIdx, reason)
 			outChan <- rune(n + '0')
 		case game.MakeChallenge, game.ChallengeBlock:
 			time.Sleep(time.Duration(waitTime) * time.Millisecond)
-			roll := ih.rng.IntN(100)
-			if roll < challengeRate {
+			st = ih.botView()
+			challenge, reason := st.decideChallenge(pIdx, rng)
+			ih.explain(pIdx, reason)
+			if challenge {
 				outChan <- rune(1 + '0')
 				continue
 			}
 			outChan <- rune(0 + '0')
 		case game.MakeBlock:
 			time.Sleep(time.Duration(waitTime) * time.Millisecond)
-			hand := ih.activePlayers[0].CardsHeld
-			if ih.action == game.Assassinate && slices.Contains(hand, game.Contessa) {
-				outChan <- rune(1 + '0')
-				continue
-			}
-			if ih.action == game.ForeignAid && slices.Contains(hand, game.Duke) {
-				outChan <- rune(1 + '0')
-				continue
-			}
-			if ih.action == game.Steal && slices.Contains(hand, game.Ambassador) {
-				outChan <- rune(1 + '0')
-				continue
-			}
-			if ih.action == game.Steal && slices.Contains(hand, game.Captain) {
-				outChan <- rune(2 + '0')
-				continue
-			}
-			roll := ih.rng.IntN(100) // Roll a d100 if no card to block
-			if roll >= challengeRate {
-				outChan <- rune(0 + '0')
-				continue
-			}
-			if ih.action == game.Steal {
-				n = ih.rng.IntN(2) + 1
-				outChan <- rune(n + '0')
-				continue
-			}
-			outChan <- rune(1 + '0')
+			st = ih.botView()
+			n, reason := st.decideBlock(pIdx, rng)
+			ih.explain(pIdx, reason)
+			outChan <- rune(n + '0')
 		case game.ChallengeReveal:
 			time.Sleep(time.Duration(1500) * time.Millisecond)
-			hand := ih.activePlayers[0].CardsHeld
+			st = ih.botView()
+			hand := st.activePlayers[0].CardsHeld
 			if len(hand) == 0 {
 				retryCounter++
 				continue
@@ -376,24 +436,13 @@ func (ih *InputHandler) CreateBotInputStream(ctx context.Context, outChan chan<-
 				panic("retried ChallengeReveal 10+ times")
 			}
 			retryCounter = 0
-			n = ih.rng.IntN(len(hand)) + 1
-			// Replace n if they have the correct card
-			if ih.action == game.Assassinate && slices.Contains(hand, game.Assassin) {
-				n = slices.Index(hand, game.Assassin) + 1
-			}
-			if ih.action == game.Exchange && slices.Contains(hand, game.Ambassador) {
-				n = slices.Index(hand, game.Ambassador) + 1
-			}
-			if ih.action == game.Steal && slices.Contains(hand, game.Captain) {
-				n = slices.Index(hand, game.Captain) + 1
-			}
-			if ih.action == game.Tax && slices.Contains(hand, game.Duke) {
-				n = slices.Index(hand, game.Duke) + 1
-			}
+			n, reason := st.pickReveal(hand, st.action.Card(), rng)
+			ih.explain(pIdx, reason)
 			outChan <- rune(n + '0')
 		case game.BlockReveal:
 			time.Sleep(time.Duration(1500) * time.Millisecond)
-			hand := ih.activePlayers[0].CardsHeld
+			st = ih.botView()
+			hand := st.activePlayers[0].CardsHeld
 			if len(hand) == 0 {
 				retryCounter++
 				continue
@@ -402,24 +451,13 @@ func (ih *InputHandler) CreateBotInputStream(ctx context.Context, outChan chan<-
 				panic("retried BlockReveal 10+ times")
 			}
 			retryCounter = 0
-			n = ih.rng.IntN(len(hand)) + 1
-			// Replace n if they have the correct card
-			if ih.action == game.Assassinate && slices.Contains(hand, game.Contessa) {
-				n = slices.Index(hand, game.Contessa) + 1
-			}
-			if ih.action == game.ForeignAid && slices.Contains(hand, game.Duke) {
-				n = slices.Index(hand, game.Duke) + 1
-			}
-			if ih.blockType == game.Ambassador && slices.Contains(hand, game.Ambassador) {
-				n = slices.Index(hand, game.Ambassador) + 1
-			}
-			if ih.blockType == game.Captain && slices.Contains(hand, game.Captain) {
-				n = slices.Index(hand, game.Captain) + 1
-			}
+			n, reason := st.pickReveal(hand, st.blockType, rng)
+			ih.explain(pIdx, reason)
 			outChan <- rune(n + '0')
 		case game.ChallengeLoss, game.BlockLoss:
 			time.Sleep(time.Duration(1500) * time.Millisecond)
-			hand := ih.activePlayers[0].CardsHeld
+			st = ih.botView()
+			hand := st.activePlayers[0].CardsHeld
 			if len(hand) == 0 {
 				retryCounter++
 				continue
@@ -428,16 +466,17 @@ func (ih *InputHandler) CreateBotInputStream(ctx context.Context, outChan chan<-
 				panic("retried Challenge/BlockLoss 10+ times")
 			}
 			retryCounter = 0
-			n = ih.rng.IntN(len(hand)) + 1
+			n = rng.IntN(len(hand)) + 1
 			outChan <- rune(n + '0')
 		case game.ResolveAction:
 			time.Sleep(time.Duration(1500) * time.Millisecond)
-			if ih.action == game.Assassinate || ih.action == game.Coup {
-				if len(ih.activePlayers) == 0 {
+			st = ih.botView()
+			if st.action == game.Assassinate || st.action == game.Coup {
+				if len(st.activePlayers) == 0 {
 					retryCounter++
 					continue
 				}
-				hand := ih.activePlayers[0].CardsHeld
+				hand := st.activePlayers[0].CardsHeld
 				if len(hand) == 0 {
 					retryCounter++
 					continue
@@ -446,14 +485,15 @@ func (ih *InputHandler) CreateBotInputStream(ctx context.Context, outChan chan<-
 					panic("retried ResolveAction Assassinate 10+ times")
 				}
 				retryCounter = 0
-				n = ih.rng.IntN(len(hand)) + 1
+				n = rng.IntN(len(hand)) + 1
 				outChan <- rune(n + '0')
 			}
 			// If not Assassinate, Coup or Exchange, no input is required, so
 			// just continue to get to ExchangeMiddle
 		case game.ExchangeMiddle:
 			time.Sleep(time.Duration(1500) * time.Millisecond)
-			handLength := len(ih.activePlayers[0].CardsHeld)
+			st = ih.botView()
+			handLength := len(st.activePlayers[0].CardsHeld)
 			if handLength == 0 {
 				retryCounter++
 				continue
@@ -462,12 +502,13 @@ func (ih *InputHandler) CreateBotInputStream(ctx context.Context, outChan chan<-
 				panic("retried ExchangeMiddle 10+ times")
 			}
 			retryCounter = 0
-			n = ih.rng.IntN(handLength) + 1
+			n = rng.IntN(handLength) + 1
 			outChan <- rune(n + '0')
 		case game.ExchangeFinal:
 			time.Sleep(time.Duration(1500) * time.Millisecond)
+			st = ih.botView()
 			// Bots don't need to cancel, so just return a number randomly.
-			handLength := len(ih.activePlayers[0].CardsHeld)
+			handLength := len(st.activePlayers[0].CardsHeld)
 			if handLength == 0 {
 				retryCounter++
 				continue
@@ -476,7 +517,7 @@ func (ih *InputHandler) CreateBotInputStream(ctx context.Context, outChan chan<-
 				panic("retried ExchangeFinal 10+ times")
 			}
 			retryCounter = 0
-			n = ih.rng.IntN(handLength) + 1
+			n = rng.IntN(handLength) + 1
 			outChan <- rune(n + '0')
 		case game.EndGame:
 			return
@@ -490,26 +531,125 @@ func (ih *InputHandler) CreateBotInputStream(ctx context.Context, outChan chan<-
 // This is where the code to quit the game on 'q' exists and handles errors
 // that may arise from reading stdin.
 func (ih *InputHandler) CreateHumanInputStream(ctx context.Context, outChan chan<- rune) {
+	ih.readKeys(ctx, Stdin(), outChan)
+}
+
+// CreateInputStream is CreateHumanInputStream for a player typing somewhere
+// other than stdin, such as a remote terminal, whose keys come from keys.
+func (ih *InputHandler) CreateInputStream(ctx context.Context, keys *Decoder, outChan chan<- rune) {
+	ih.readKeys(ctx, keys, outChan)
+}
+
+func (ih *InputHandler) readKeys(ctx context.Context, keys *Decoder, outChan chan<- rune) {
 	defer ih.RecoverPanic("Panic captured by CreateHumanInputStream")
-	var buf = make([]byte, 1)
 	for {
-		_, err := os.Stdin.Read(buf)
+		ev, err := keys.Next()
 		if err != nil {
 			// ih.chanErr <- fmt.Errorf("Error reading from stdin: %w", err)
 			panic(err)
 		}
-		if rune(buf[0]) == 'q' {
+		// While the player is typing, every key is part of what they type.
+		typing := ih.typing.Load()
+		key := ev.Key
+		switch {
+		case ev.Paste != "":
+			// Pasted text only means anything in the middle of a line.
+			if !typing {
+				continue
+			}
+			for _, r := range ev.Paste {
+				if r == '\r' || r == '\n' {
+					// Only the first line of a paste is kept, so as not to
+					// send the line half way through it.
+					break
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case outChan <- r:
+				}
+			}
+			continue
+		case ev.Alt:
+			continue
+		case ev.Click:
+			if ih.menu == nil || typing {
+				continue
+			}
+			var ok bool
+			if key, ok = ih.menu.Click(ev.Row, ev.Col); !ok {
+				continue
+			}
+		}
+		if key == 'q' && !typing {
 			ih.chanErr <- fmt.Errorf("User Quit")
 		}
+		if key == 't' && !typing {
+			ih.showThoughts.Store(!ih.showThoughts.Load())
+			ih.notify()
+			continue
+		}
+		if key == '?' && !typing {
+			ih.hintRequested.Store(true)
+			ih.notify()
+			continue
+		}
+		if ih.menu != nil && !typing {
+			var moved bool
+			if key, moved = ih.menu.Navigate(key); moved {
+				continue
+			}
+		}
 		select {
 		case <-ctx.Done():
 			return
-		case outChan <- rune(buf[0]):
+		case outChan <- key:
 			continue
 		}
 	}
 }
 
+// SetTyping tells the input stream whether the player is typing text, in
+// which case keys like 'q' are passed on rather than acted on.
+func (ih *InputHandler) SetTyping(typing bool) {
+	ih.typing.Store(typing)
+}
+
+// Changes tells the game loop that something it draws has changed outside a
+// move, such as a player answering a challenge or a bot explaining itself.
+func (ih *InputHandler) Changes() <-chan struct{} {
+	return ih.changed
+}
+
+// notify sends on Changes, unless a change is already waiting to be seen.
+func (ih *InputHandler) notify() {
+	select {
+	case ih.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Navigator moves a cursor over the moves on screen. Navigate is given each
+// key before it is passed on, and either uses it to move the cursor or
+// returns the key to pass on in its place. Click finds the move drawn at a
+// row and column, and the key that makes it.
+type Navigator interface {
+	Navigate(key rune) (out rune, moved bool)
+	Click(row, col int) (key rune, ok bool)
+}
+
+// SetMenu has the input stream steer menu with the arrow keys. It must be
+// called before the stream is started.
+func (ih *InputHandler) SetMenu(menu Navigator) {
+	ih.menu = menu
+}
+
+// HintRequested reports whether the local player has asked the advisor for a
+// hint since the last call.
+func (ih *InputHandler) HintRequested() bool {
+	return ih.hintRequested.Swap(false)
+}
+
 // Scratch
 
 func (ih *InputHandler) RecoverPanic(msg string) {
@@ -0,0 +1,307 @@
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Keys that a terminal sends as escape sequences are handed on as runes from
+// Unicode's private use area, which no keyboard types.
+const (
+	KeyUp rune = 0xE000 + iota
+	KeyDown
+	KeyRight
+	KeyLeft
+	KeyHome
+	KeyEnd
+	KeyInsert
+	KeyDelete
+	KeyPageUp
+	KeyPageDown
+	KeyF1
+	KeyF2
+	KeyF3
+	KeyF4
+	KeyF5
+	KeyF6
+	KeyF7
+	KeyF8
+	KeyF9
+	KeyF10
+	KeyF11
+	KeyF12
+)
+
+// KeyEscape is the Escape key on its own.
+const KeyEscape rune = 27
+
+// Bracketed paste has the terminal mark out pasted text, which a Decoder
+// hands on in one piece, once PasteOn has been written to it.
+const (
+	PasteOn  = "\033[?2004h"
+	PasteOff = "\033[?2004l"
+)
+
+// pasteEnd is what a terminal sends at the end of pasted text.
+const pasteEnd = "\033[201~"
+
+// Event is a key pressed, a place clicked or some text pasted on a terminal.
+// Key is 0 for clicks and pastes. Clicks are left clicks, at a row and column
+// counted from 1.
+type Event struct {
+	Key      rune
+	Alt      bool
+	Click    bool
+	Row, Col int
+	Paste    string
+}
+
+// escapeWait is how long an escape is given for the rest of a sequence to
+// follow it before it is taken for the Escape key. A sequence can be split
+// across reads, by a slow connection or a full buffer, but the gap is short.
+const escapeWait = 50 * time.Millisecond
+
+// Decoder turns what a terminal sends into Events: runes decoded from UTF-8,
+// and the escape sequences sent for keys such as the arrows, for mouse
+// clicks, and around pasted text.
+type Decoder struct {
+	mu   sync.Mutex
+	in   *bufio.Reader
+	feed *feed
+}
+
+// NewDecoder decodes the terminal behind in. It is read from then on, until
+// it fails, whether or not Next is called.
+func NewDecoder(in io.Reader) *Decoder {
+	f := &feed{chunks: make(chan []byte)}
+	go f.fill(in)
+	return &Decoder{in: bufio.NewReader(f), feed: f}
+}
+
+// feed reads ahead of a Decoder, so it can tell whether more is coming
+// without blocking on it.
+type feed struct {
+	chunks chan []byte
+	rest   []byte
+	// err is why in stopped, which is set before chunks is closed.
+	err error
+}
+
+// fill hands on what is read from in, until it fails.
+func (f *feed) fill(in io.Reader) {
+	for {
+		buf := make([]byte, 256)
+		n, err := in.Read(buf)
+		if n > 0 {
+			f.chunks <- buf[:n]
+		}
+		if err != nil {
+			f.err = err
+			close(f.chunks)
+			return
+		}
+	}
+}
+
+func (f *feed) Read(p []byte) (int, error) {
+	if len(f.rest) == 0 {
+		chunk, ok := <-f.chunks
+		if !ok {
+			return 0, f.err
+		}
+		f.rest = chunk
+	}
+	n := copy(p, f.rest)
+	f.rest = f.rest[n:]
+	return n, nil
+}
+
+// more waits up to d for something to read, and reports whether it came.
+func (f *feed) more(d time.Duration) bool {
+	if len(f.rest) > 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case chunk, ok := <-f.chunks:
+		if !ok {
+			return false
+		}
+		f.rest = chunk
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// Stdin is the Decoder for the terminal on stdin. Everything reading the
+// keyboard shares it, so nothing it has read ahead is lost between them.
+var Stdin = sync.OnceValue(func() *Decoder {
+	return NewDecoder(os.Stdin)
+})
+
+// Next returns the next Event. Sequences that mean nothing here, and bytes
+// that aren't UTF-8, are skipped.
+func (dec *Decoder) Next() (Event, error) {
+	dec.mu.Lock()
+	defer dec.mu.Unlock()
+	for {
+		b, err := dec.in.ReadByte()
+		if err != nil {
+			return Event{}, err
+		}
+		switch {
+		case b >= utf8.RuneSelf:
+			dec.in.UnreadByte()
+			r, size, err := dec.in.ReadRune()
+			if err != nil {
+				return Event{}, err
+			}
+			if r == utf8.RuneError && size == 1 {
+				continue
+			}
+			return Event{Key: r}, nil
+		case b != 27:
+			return Event{Key: rune(b)}, nil
+		case dec.in.Buffered() == 0 && !dec.feed.more(escapeWait):
+			// An escape with nothing soon after it is the Escape key
+			// itself.
+			return Event{Key: rune(b)}, nil
+		}
+		b, _ = dec.in.ReadByte()
+		if b != '[' && b != 'O' {
+			// Alt and a key.
+			dec.in.UnreadByte()
+			r, _, err := dec.in.ReadRune()
+			if err != nil {
+				return Event{}, err
+			}
+			return Event{Key: r, Alt: true}, nil
+		}
+		if ev, ok := dec.sequence(b); ok {
+			return ev, nil
+		}
+	}
+}
+
+// sequence reads the rest of a control sequence, after its "ESC [" or
+// "ESC O", and reports which Event it stands for, if any.
+func (dec *Decoder) sequence(intro byte) (Event, bool) {
+	var params []byte
+	for {
+		b, err := dec.in.ReadByte()
+		if err != nil {
+			return Event{}, false
+		}
+		if b < 0x40 || b > 0x7e {
+			// Parameters and intermediates run up to the final byte.
+			params = append(params, b)
+			continue
+		}
+		if len(params) > 0 && params[0] == '<' {
+			return mouse(string(params[1:]), b == 'M')
+		}
+		// Modifiers held down come after a semicolon, and are ignored.
+		param, _, _ := strings.Cut(string(params), ";")
+		var key rune
+		switch b {
+		case 'A':
+			key = KeyUp
+		case 'B':
+			key = KeyDown
+		case 'C':
+			key = KeyRight
+		case 'D':
+			key = KeyLeft
+		case 'H':
+			key = KeyHome
+		case 'F':
+			key = KeyEnd
+		case 'P', 'Q', 'R', 'S':
+			if intro == 'O' || param == "1" {
+				key = KeyF1 + rune(b-'P')
+			}
+		case '~':
+			if param == "200" {
+				return dec.paste()
+			}
+			key = tildeKeys[param]
+		}
+		return Event{Key: key}, key != 0
+	}
+}
+
+// tildeKeys are the keys sent as "ESC [ n ~", by their n.
+var tildeKeys = map[string]rune{
+	"1":  KeyHome,
+	"2":  KeyInsert,
+	"3":  KeyDelete,
+	"4":  KeyEnd,
+	"5":  KeyPageUp,
+	"6":  KeyPageDown,
+	"7":  KeyHome,
+	"8":  KeyEnd,
+	"11": KeyF1,
+	"12": KeyF2,
+	"13": KeyF3,
+	"14": KeyF4,
+	"15": KeyF5,
+	"17": KeyF6,
+	"18": KeyF7,
+	"19": KeyF8,
+	"20": KeyF9,
+	"21": KeyF10,
+	"23": KeyF11,
+	"24": KeyF12,
+}
+
+// paste reads pasted text up to the sequence that ends it.
+func (dec *Decoder) paste() (Event, bool) {
+	var text []byte
+	for !bytes.HasSuffix(text, []byte(pasteEnd)) {
+		b, err := dec.in.ReadByte()
+		if err != nil {
+			break
+		}
+		text = append(text, b)
+	}
+	pasted := strings.TrimSuffix(string(text), pasteEnd)
+	return Event{Paste: strings.ToValidUTF8(pasted, "")}, pasted != ""
+}
+
+// mouse decodes the "button;column;row" of an SGR mouse report. Only a left
+// button being pressed counts as a click. The wheel moves as the arrow keys
+// do, and everything else is ignored.
+func mouse(params string, press bool) (Event, bool) {
+	fields := strings.Split(params, ";")
+	if len(fields) != 3 || !press {
+		return Event{}, false
+	}
+	var n [3]int
+	for i, f := range fields {
+		var err error
+		if n[i], err = strconv.Atoi(f); err != nil {
+			return Event{}, false
+		}
+	}
+	// The low bits are the button, 4, 8 and 16 are modifiers held down, 32
+	// is a drag and 64 the wheel.
+	button := n[0] &^ (4 | 8 | 16)
+	switch button {
+	case 0:
+		return Event{Click: true, Row: n[2], Col: n[1]}, true
+	case 64:
+		return Event{Key: KeyUp}, true
+	case 65:
+		return Event{Key: KeyDown}, true
+	}
+	return Event{}, false
+}
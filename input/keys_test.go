@@ -0,0 +1,109 @@
+package input
+
+import (
+	"context"
+	"io"
+	"slices"
+	"testing"
+	"time"
+)
+
+// chunkReader hands out parts one to a Read, as a terminal would write them,
+// waiting gap before each after the first.
+type chunkReader struct {
+	parts []string
+	gap   time.Duration
+	read  int
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if r.read == len(r.parts) {
+		return 0, io.EOF
+	}
+	if r.read > 0 {
+		time.Sleep(r.gap)
+	}
+	n := copy(p, r.parts[r.read])
+	r.read++
+	return n, nil
+}
+
+func TestDecoder(t *testing.T) {
+	tests := []struct {
+		name  string
+		parts []string
+		gap   time.Duration
+		want  []Event
+	}{
+		{"ascii", []string{"ab"}, 0, []Event{{Key: 'a'}, {Key: 'b'}}},
+		{"utf8", []string{"é中"}, 0, []Event{{Key: 'é'}, {Key: '中'}}},
+		{"utf8 split", []string{"\xe4", "\xb8\xad"}, 0, []Event{{Key: '中'}}},
+		{"utf8 split by a pause", []string{"\xe4\xb8", "\xad"}, escapeWait / 5, []Event{{Key: '中'}}},
+		{"not utf8", []string{"a\xffb"}, 0, []Event{{Key: 'a'}, {Key: 'b'}}},
+		{"arrow", []string{"\033[A"}, 0, []Event{{Key: KeyUp}}},
+		{"arrow split after escape", []string{"\033", "[B"}, 0, []Event{{Key: KeyDown}}},
+		{"arrow split by a pause", []string{"\033", "[C"}, escapeWait / 5, []Event{{Key: KeyRight}}},
+		{"arrow split before the end", []string{"\033[", "D"}, 0, []Event{{Key: KeyLeft}}},
+		{"modifier held", []string{"\033[1;5C"}, 0, []Event{{Key: KeyRight}}},
+		{"application mode", []string{"\033OH\033OP"}, 0, []Event{{Key: KeyHome}, {Key: KeyF1}}},
+		{"tilde", []string{"\033[3~\033[15~"}, 0, []Event{{Key: KeyDelete}, {Key: KeyF5}}},
+		{"unknown sequence", []string{"\033[Zq"}, 0, []Event{{Key: 'q'}}},
+		{"alt", []string{"\033x"}, 0, []Event{{Key: 'x', Alt: true}}},
+		{"alt split", []string{"\033", "x"}, 0, []Event{{Key: 'x', Alt: true}}},
+		{"escape at the end", []string{"a\033"}, 0, []Event{{Key: 'a'}, {Key: KeyEscape}}},
+		{"escape then a key", []string{"\033", "x"}, 2 * escapeWait, []Event{{Key: KeyEscape}, {Key: 'x'}}},
+		{"click", []string{"\033[<0;10;5M"}, 0, []Event{{Click: true, Row: 5, Col: 10}}},
+		{"click split", []string{"\033[<0;1", "0;5M"}, 0, []Event{{Click: true, Row: 5, Col: 10}}},
+		{"release", []string{"\033[<0;10;5mq"}, 0, []Event{{Key: 'q'}}},
+		{"right click", []string{"\033[<2;10;5Mq"}, 0, []Event{{Key: 'q'}}},
+		{"wheel", []string{"\033[<64;1;1M\033[<65;1;1M"}, 0, []Event{{Key: KeyUp}, {Key: KeyDown}}},
+		{"paste", []string{"\033[200~hi\r\nthere\033[201~"}, 0, []Event{{Paste: "hi\r\nthere"}}},
+		{"paste split", []string{"\033[200~he", "llo\033[2", "01~a"}, 0, []Event{{Paste: "hello"}, {Key: 'a'}}},
+		{"paste with escapes", []string{"\033[200~\033[A\033[201~"}, 0, []Event{{Paste: "\033[A"}}},
+		{"empty paste", []string{"\033[200~\033[201~a"}, 0, []Event{{Key: 'a'}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewDecoder(&chunkReader{parts: tt.parts, gap: tt.gap})
+			var got []Event
+			for {
+				ev, err := dec.Next()
+				if err != nil {
+					break
+				}
+				got = append(got, ev)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPasteWhileTyping(t *testing.T) {
+	chanErr := make(chan error, 1)
+	ih := NewInputHandler(nil, chanErr)
+	ih.SetTyping(true)
+	dec := NewDecoder(&chunkReader{parts: []string{"\033[200~hi\r\nthere\033[201~", "x"}})
+	keys := make(chan rune)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ih.CreateInputStream(ctx, dec, keys)
+
+	var got []rune
+	for {
+		select {
+		case r := <-keys:
+			got = append(got, r)
+			continue
+		case <-chanErr:
+			// The reader has run out.
+		case <-time.After(5 * time.Second):
+			t.Fatalf("keys still coming after %q", string(got))
+		}
+		break
+	}
+	if string(got) != "hix" {
+		t.Errorf("got %q, want %q", string(got), "hix")
+	}
+}
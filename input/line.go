@@ -0,0 +1,92 @@
+package input
+
+import "unicode"
+
+// Line is a line of text being typed, with a cursor that moves about in it
+// as it would in a shell.
+type Line struct {
+	text   []rune
+	cursor int
+}
+
+// Press handles an Event, and reports whether it finished the line.
+func (l *Line) Press(ev Event) (done bool) {
+	if ev.Paste != "" {
+		for _, r := range ev.Paste {
+			if r == '\r' || r == '\n' {
+				// Only the first line of a paste is kept.
+				break
+			}
+			l.insert(r)
+		}
+		return false
+	}
+	if ev.Alt || ev.Click {
+		return false
+	}
+	switch ev.Key {
+	case '\r', '\n':
+		return true
+	case 127, '\b':
+		if l.cursor > 0 {
+			start := l.left(l.cursor)
+			l.text = append(l.text[:start], l.text[l.cursor:]...)
+			l.cursor = start
+		}
+	case KeyDelete, 4: // Ctrl-D
+		if l.cursor < len(l.text) {
+			l.text = append(l.text[:l.cursor], l.text[l.right(l.cursor):]...)
+		}
+	case KeyLeft, 2: // Ctrl-B
+		l.cursor = l.left(l.cursor)
+	case KeyRight, 6: // Ctrl-F
+		l.cursor = l.right(l.cursor)
+	case KeyHome, 1: // Ctrl-A
+		l.cursor = 0
+	case KeyEnd, 5: // Ctrl-E
+		l.cursor = len(l.text)
+	case 21: // Ctrl-U
+		l.text, l.cursor = l.text[l.cursor:], 0
+	default:
+		l.insert(ev.Key)
+	}
+	return false
+}
+
+// insert puts r in at the cursor, if it is something that can be shown.
+func (l *Line) insert(r rune) {
+	if !unicode.IsPrint(r) && !unicode.In(r, unicode.Mn, unicode.Me) {
+		return
+	}
+	l.text = append(l.text[:l.cursor], append([]rune{r}, l.text[l.cursor:]...)...)
+	l.cursor++
+}
+
+// left is where the character before i starts, counting any marks on it as
+// part of it.
+func (l *Line) left(i int) int {
+	i = max(i-1, 0)
+	for i > 0 && RuneWidth(l.text[i]) == 0 {
+		i--
+	}
+	return i
+}
+
+// right is where the character after the one at i starts.
+func (l *Line) right(i int) int {
+	i = min(i+1, len(l.text))
+	for i < len(l.text) && RuneWidth(l.text[i]) == 0 {
+		i++
+	}
+	return i
+}
+
+// String is the text typed so far.
+func (l *Line) String() string {
+	return string(l.text)
+}
+
+// Cursor splits the text either side of the cursor.
+func (l *Line) Cursor() (before, after string) {
+	return string(l.text[:l.cursor]), string(l.text[l.cursor:])
+}
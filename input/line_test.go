@@ -0,0 +1,71 @@
+package input
+
+import "testing"
+
+// keys are Events for each rune of s.
+func keys(s string) []Event {
+	var evs []Event
+	for _, r := range s {
+		evs = append(evs, Event{Key: r})
+	}
+	return evs
+}
+
+func TestLine(t *testing.T) {
+	left, right := Event{Key: KeyLeft}, Event{Key: KeyRight}
+	backspace := Event{Key: 127}
+	tests := []struct {
+		name   string
+		events []Event
+		text   string
+		before string
+		done   bool
+	}{
+		{"typing", keys("abc"), "abc", "abc", false},
+		{"enter", keys("ab\r"), "ab", "ab", true},
+		{"newline", keys("ab\n"), "ab", "ab", true},
+		{"insert", append(keys("ab"), left, Event{Key: 'x'}), "axb", "ax", false},
+		{"left past the start", append(keys("ab"), left, left, left), "ab", "", false},
+		{"right past the end", append(keys("ab"), left, right, right), "ab", "ab", false},
+		{"home", append(keys("ab"), Event{Key: KeyHome}, Event{Key: 'x'}), "xab", "x", false},
+		{"end", append(keys("ab"), Event{Key: 1}, Event{Key: KeyEnd}, Event{Key: 'x'}), "abx", "abx", false},
+		{"ctrl-b and ctrl-f", append(keys("ab"), Event{Key: 2}, Event{Key: 2}, Event{Key: 6}), "ab", "a", false},
+		{"backspace", append(keys("abc"), left, backspace), "ac", "a", false},
+		{"backspace at the start", append(keys("ab"), Event{Key: KeyHome}, backspace), "ab", "", false},
+		{"ctrl-h", append(keys("ab"), Event{Key: '\b'}), "a", "a", false},
+		{"delete", append(keys("abc"), left, left, Event{Key: KeyDelete}), "ac", "a", false},
+		{"delete at the end", append(keys("ab"), Event{Key: 4}), "ab", "ab", false},
+		{"ctrl-u", append(keys("abc"), left, Event{Key: 21}), "c", "", false},
+		{"wide", append(keys("中文"), left, Event{Key: 'x'}), "中x文", "中x", false},
+		{"mark moves with its letter", append(keys("e\u0301a"), left, left), "e\u0301a", "", false},
+		{"backspace takes the mark", append(keys("ae\u0301"), backspace), "a", "a", false},
+		{"delete takes the mark", append(keys("e\u0301a"), Event{Key: KeyHome}, Event{Key: KeyDelete}), "a", "", false},
+		{"control keys", keys("a\tb\x00"), "ab", "ab", false},
+		{"alt", []Event{{Key: 'a'}, {Key: 'b', Alt: true}}, "a", "a", false},
+		{"click", []Event{{Key: 'a'}, {Click: true, Row: 1, Col: 1}}, "a", "a", false},
+		{"escape", []Event{{Key: 'a'}, {Key: KeyEscape}}, "a", "a", false},
+		{"paste", []Event{{Paste: "Bob"}}, "Bob", "Bob", false},
+		{"paste at the cursor", append(keys("ab"), left, Event{Paste: "XY"}), "aXYb", "aXY", false},
+		{"paste keeps the first line", []Event{{Paste: "Bob\r\nAnn"}}, "Bob", "Bob", false},
+		{"paste drops control keys", []Event{{Paste: "B\to\033b"}}, "Bob", "Bob", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var l Line
+			var done bool
+			for _, ev := range tt.events {
+				done = l.Press(ev)
+			}
+			if got := l.String(); got != tt.text {
+				t.Errorf("text: got %q, want %q", got, tt.text)
+			}
+			before, after := l.Cursor()
+			if before != tt.before || before+after != tt.text {
+				t.Errorf("cursor: got %q|%q, want %q before it", before, after, tt.before)
+			}
+			if done != tt.done {
+				t.Errorf("done: got %v, want %v", done, tt.done)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package input
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// wide are the runes a terminal draws two columns wide: the CJK scripts,
+// Hangul, fullwidth forms and most emoji.
+var wide = [][2]rune{
+	{0x1100, 0x115f},
+	{0x2e80, 0x303e},
+	{0x3041, 0x33ff},
+	{0x3400, 0x4dbf},
+	{0x4e00, 0x9fff},
+	{0xa000, 0xa4cf},
+	{0xac00, 0xd7a3},
+	{0xf900, 0xfaff},
+	{0xfe30, 0xfe4f},
+	{0xff00, 0xff60},
+	{0xffe0, 0xffe6},
+	{0x1f300, 0x1f64f},
+	{0x1f900, 0x1f9ff},
+	{0x20000, 0x3fffd},
+}
+
+// RuneWidth is how many columns a terminal takes to draw r. Accents and
+// other marks that sit on the character before them take none.
+func RuneWidth(r rune) int {
+	if r == 0 || unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	for _, span := range wide {
+		if r >= span[0] && r <= span[1] {
+			return 2
+		}
+	}
+	return 1
+}
+
+// StringWidth is how many columns a terminal takes to draw s, which must not
+// hold colour codes.
+func StringWidth(s string) int {
+	n := 0
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		n += RuneWidth(r)
+		s = s[size:]
+	}
+	return n
+}
@@ -0,0 +1,54 @@
+package input
+
+import "testing"
+
+func TestRuneWidth(t *testing.T) {
+	tests := []struct {
+		name string
+		r    rune
+		want int
+	}{
+		{"ascii", 'a', 1},
+		{"accented", '\u00e9', 1},
+		{"greek", 'λ', 1},
+		{"combining mark", '\u0301', 0},
+		{"enclosing mark", '\u20dd', 0},
+		{"zero width space", '\u200b', 0},
+		{"nul", 0, 0},
+		{"han", '中', 2},
+		{"hiragana", 'あ', 2},
+		{"hangul", '한', 2},
+		{"hangul jamo", '\u1100', 2},
+		{"fullwidth", 'Ａ', 2},
+		{"halfwidth katakana", 'ｱ', 1},
+		{"emoji", '😀', 2},
+		{"cards", '🂡', 1},
+		{"private use", KeyUp, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RuneWidth(tt.r); got != tt.want {
+				t.Errorf("RuneWidth(%U): got %d, want %d", tt.r, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"Bob", 3},
+		{"Zoë", 3},
+		{"Zoë", 3},
+		{"李雷", 4},
+		{"Ann 😀", 6},
+	}
+	for _, tt := range tests {
+		if got := StringWidth(tt.s); got != tt.want {
+			t.Errorf("StringWidth(%q): got %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
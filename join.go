@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"kugo/client"
+	dis "kugo/display"
+	"kugo/game"
+	inp "kugo/input"
+	"kugo/server"
+)
+
+// session is a connection to a server together with the local screen and
+// keyboard used to play on it.
+type session struct {
+	cl           *client.Client
+	network      string
+	addr         string
+	display      *dis.Display
+	inputHandler *inp.InputHandler
+	keys         chan rune
+	msgs         chan *server.Message
+	chanErr      chan error
+}
+
+// How long to keep trying to get back into a game after losing the
+// connection. It is longer than the server's grace period, as a bot can hand
+// the seat back.
+const reconnectFor = 2 * time.Minute
+
+// openSession starts reading the keyboard and the server's messages for cl.
+// Both stop when ctx is done.
+func openSession(ctx context.Context, cl *client.Client, network, addr string, display *dis.Display, chanErr chan error) *session {
+	s := session{
+		cl:      cl,
+		network: network,
+		addr:    addr,
+		display: display,
+		keys:    make(chan rune),
+		chanErr: chanErr,
+	}
+	// No players are handed to the InputHandler as it is only used for its
+	// keyboard stream; the server does the validating.
+	s.inputHandler = inp.NewInputHandler(nil, chanErr)
+	s.inputHandler.SetMenu(display)
+	go s.inputHandler.CreateHumanInputStream(ctx, s.keys)
+	s.listen(ctx)
+	return &s
+}
+
+// listen passes on the messages from s.cl until it hangs up, then closes
+// s.msgs.
+func (s *session) listen(ctx context.Context) {
+	cl, msgs := s.cl, make(chan *server.Message)
+	s.msgs = msgs
+	go func() {
+		defer close(msgs)
+		for {
+			msg, err := cl.Receive()
+			if err != nil {
+				return
+			}
+			select {
+			case msgs <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// reconnect tries to take the player's seat back after the connection drops,
+// until it works or reconnectFor has passed.
+func (s *session) reconnect(ctx context.Context) error {
+	deadline := time.After(reconnectFor)
+	for {
+		s.display.DrawMessage(fmt.Sprintf("Lost connection to %s, reconnecting...", s.addr))
+		cl, err := client.Rejoin(s.network, s.addr, s.cl.Token)
+		if err == nil {
+			cl.KeepKeys(s.cl)
+			s.cl.Close()
+			s.cl = cl
+			s.listen(ctx)
+			return nil
+		}
+		game.Debugf("reconnecting: %v", err)
+		select {
+		case <-time.After(time.Second):
+		case <-deadline:
+			return fmt.Errorf("lost connection to %s", s.addr)
+		case err := <-s.chanErr:
+			return err
+		}
+	}
+}
+
+// chatLines is how much of the chat the display keeps on screen.
+const chatLines = 8
+
+// draw keeps the display drawing until the returned function is called.
+func (s *session) draw(ctx context.Context) func() {
+	ctx, stop := context.WithCancel(ctx)
+	go s.display.DrawDisplay(ctx)
+	return stop
+}
+
+// JoinLoop plays a game hosted elsewhere. It draws the views sent by the
+// server with the same Display as a local game, and forwards the local
+// player's number keys to the server as moves.
+func JoinLoop(network, addr, name string) error {
+	var chanErr = make(chan error)
+	var err error
+	if name == "" {
+		name, err = GetPlayerName()
+		if err != nil {
+			return err
+		}
+	}
+
+	display := dis.NewDisplay(chanErr)
+	display.DrawMessage(fmt.Sprintf("Connecting to %s...", addr))
+	cl, err := client.Dial(network, addr, name)
+	if err != nil {
+		return err
+	}
+	defer cl.Close()
+	display.DrawMessage("Seated! Waiting for the other players to join...")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	return openSession(ctx, cl, network, addr, display, chanErr).play(ctx, true)
+}
+
+// WatchLoop spectates a game hosted elsewhere.
+func WatchLoop(network, addr string) error {
+	var chanErr = make(chan error)
+	display := dis.NewDisplay(chanErr)
+	display.DrawMessage(fmt.Sprintf("Connecting to %s...", addr))
+	cl, err := client.Watch(network, addr)
+	if err != nil {
+		return err
+	}
+	defer cl.Close()
+	display.DrawMessage("Waiting for the game to start...")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	return openSession(ctx, cl, network, addr, display, chanErr).play(ctx, false)
+}
+
+// play draws the game until it ends. The advisor is only asked for hints if
+// the game's rules allow it.
+func (s *session) play(ctx context.Context, advisor bool) error {
+	var view *game.View
+	var commitment string
+	var witness game.Witness
+	var checked bool
+	var stopDrawing func()
+	defer func() {
+		if stopDrawing != nil {
+			stopDrawing()
+		}
+	}()
+	msgs := s.msgs
+	var chat []string
+	var entry inp.ChatEntry
+	s.display.UpdateChat(chat)
+	hintChan := make(chan advice)
+	// Hint requests don't arrive on a channel, so wake up now and then to
+	// check for them.
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case msg, ok := <-msgs:
+			if !ok {
+				// The server hangs up once the game is over, so that's only a
+				// problem if it happens early. Otherwise leave the victory
+				// screen up until the player quits.
+				if view != nil && view.Phase == game.EndGame {
+					msgs = nil
+					continue
+				}
+				if s.cl.Token == "" {
+					return fmt.Errorf("lost connection to %s", s.addr)
+				}
+				if stopDrawing != nil {
+					stopDrawing()
+					stopDrawing = nil
+				}
+				if err := s.reconnect(ctx); err != nil {
+					return err
+				}
+				msgs = s.msgs
+				continue
+			}
+			if msg.Type == server.MsgError {
+				game.Debugf("server error: %s", msg.Error)
+			}
+			if msg.Type == server.MsgChat {
+				chat = append(chat, fmt.Sprintf("%s: %s", msg.Name, msg.Text))
+				chat = chat[max(0, len(chat)-chatLines):]
+				s.display.UpdateChat(chat)
+				continue
+			}
+			if msg.Type != server.MsgView {
+				continue
+			}
+			view = msg.View
+			if commitment == "" {
+				commitment = view.Commitment
+			}
+			witness.See(view)
+			if view.Record != nil && !checked {
+				note := checkDeal(commitment, &witness, view.Record)
+				if err := s.cl.CheckContribution(view.Record); err != nil {
+					note = fmt.Sprintf("Warning: %v!", err)
+				}
+				s.display.UpdateNote(note)
+				checked = true
+			}
+			s.display.UpdateDisplay(view.DisplayData())
+			s.display.ClearHint()
+			if stopDrawing == nil {
+				stopDrawing = s.draw(ctx)
+			}
+		case key := <-s.keys:
+			if s.cl.Seat == game.Spectator {
+				continue
+			}
+			line, handled := entry.Press(key)
+			s.inputHandler.SetTyping(entry.Typing || entry.Emoting)
+			if entry.Emoting {
+				s.display.UpdateChatEntry(false, "", inp.Emotes)
+			} else {
+				s.display.UpdateChatEntry(entry.Typing, entry.Text(), nil)
+			}
+			if line != "" {
+				if err := s.cl.Say(line); err != nil {
+					game.Debugf("chatting: %v", err)
+				}
+			}
+			if handled || key < '0' || key > '9' {
+				continue
+			}
+			// A failed send means the connection has dropped, which is
+			// dealt with once the server's messages stop.
+			if err := s.cl.SendKey(key); err != nil {
+				game.Debugf("sending %q: %v", key, err)
+			}
+		case hint := <-hintChan:
+			if view != nil && hint.move == view.Move && hint.seat == view.Seat {
+				s.display.UpdateHint(hint.options)
+			}
+		case <-ticker.C:
+		case err := <-s.chanErr:
+			return err
+		}
+		if s.inputHandler.HintRequested() && advisor && view != nil && view.Seat != game.Spectator {
+			hintView := view
+			go func() {
+				rng := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+				hint := advice{hintView.Move, hintView.Seat, game.Advise(hintView, advisorRollouts, rng)}
+				select {
+				case hintChan <- hint:
+				case <-ctx.Done():
+				}
+			}()
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"kugo/client"
+	dis "kugo/display"
+	"kugo/server"
+)
+
+// lobbyPort is where Host opens its lobby, and where Join looks by default.
+const lobbyPort = 7777
+
+// HostLoop opens a lobby on this machine for friends to join, then sits the
+// local player in a new room with the rules chosen on the main menu.
+func HostLoop(opts *GameOptions) error {
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", lobbyPort))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lobby := server.NewLobby()
+	announce(ctx, ln, true, lobby.Rooms)
+	go lobby.Serve(ctx, ln)
+
+	room := server.RoomInfo{Players: opts.NumPlayers, Advisor: opts.Advisor}
+	return LobbyLoop("tcp", fmt.Sprintf("localhost:%d", lobbyPort), opts.UserName, &room, false)
+}
+
+// LobbyLoop connects to the lobby at addr, lets the player pick or open a
+// room, and plays the room's game once everyone in it is ready. If create is
+// given, that room is opened straight away. With watch, picking a room
+// spectates it rather than taking a seat, until the player switches.
+func LobbyLoop(network, addr, name string, create *server.RoomInfo, watch bool) error {
+	var chanErr = make(chan error)
+	display := dis.NewDisplay(chanErr)
+	display.DrawMessage(fmt.Sprintf("Connecting to %s...", addr))
+	cl, err := client.Connect(network, addr, name)
+	if err != nil {
+		return err
+	}
+	defer cl.Close()
+	if create != nil {
+		if err := cl.CreateRoom(*create); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := openSession(ctx, cl, network, addr, display, chanErr)
+	room, err := s.lobby(watch)
+	if err != nil {
+		return err
+	}
+	display.DrawMessage("Dealing...")
+	return s.play(ctx, room.Advisor)
+}
+
+// lobbyScreen is what the player is looking at in the lobby.
+type lobbyScreen struct {
+	rooms    []server.RoomInfo
+	room     *server.RoomInfo
+	creating *server.RoomInfo
+	ready    bool
+	watching bool
+	problem  string
+}
+
+// lobby runs the lobby screens until the player's room starts its game, and
+// returns the room. watching is whether picking a room watches it to begin
+// with.
+func (s *session) lobby(watching bool) (*server.RoomInfo, error) {
+	ls := lobbyScreen{watching: watching}
+	for {
+		ls.draw(s.display)
+		select {
+		case msg, ok := <-s.msgs:
+			if !ok {
+				return nil, fmt.Errorf("lost connection to %s", s.addr)
+			}
+			switch msg.Type {
+			case server.MsgRooms:
+				ls.rooms = msg.Rooms
+			case server.MsgRoom:
+				ls.room, ls.problem = msg.Room, ""
+			case server.MsgError:
+				ls.problem = msg.Error
+			case server.MsgWelcome:
+				return msg.Room, nil
+			}
+		case key := <-s.keys:
+			if err := ls.press(s.cl, key); err != nil {
+				return nil, err
+			}
+		case err := <-s.chanErr:
+			return nil, err
+		}
+	}
+}
+
+func (ls *lobbyScreen) press(cl *client.Client, key rune) error {
+	switch {
+	case ls.room != nil:
+		switch key {
+		case 'r':
+			ls.ready = !ls.ready
+			return cl.Ready(ls.ready)
+		case 'l':
+			ls.room, ls.ready = nil, false
+			return cl.LeaveRoom()
+		}
+	case ls.creating != nil:
+		switch key {
+		case '3', '4', '5', '6':
+			ls.creating.Players = int(key - '0')
+		case 'a':
+			ls.creating.Advisor = !ls.creating.Advisor
+		case 'v':
+			ls.creating.Reveal = !ls.creating.Reveal
+		case '\r', '\n':
+			room := *ls.creating
+			ls.creating = nil
+			return cl.CreateRoom(room)
+		case 'b':
+			ls.creating = nil
+		}
+	default:
+		switch {
+		case key >= '1' && key <= '9' && int(key-'1') < len(ls.rooms):
+			if ls.watching {
+				return cl.WatchRoom(ls.rooms[key-'1'].Name)
+			}
+			return cl.JoinRoom(ls.rooms[key-'1'].Name)
+		case key == 'w':
+			ls.watching = !ls.watching
+		case key == 'c':
+			ls.creating = &server.RoomInfo{Players: 4, Advisor: true}
+		case key == 'r':
+			return cl.ListRooms()
+		}
+	}
+	return nil
+}
+
+func (ls *lobbyScreen) draw(display *dis.Display) {
+	var title string
+	var lines, help []string
+	switch {
+	case ls.room != nil:
+		title = fmt.Sprintf("%s (%s)", ls.room.Name, describeRules(ls.room))
+		for _, m := range ls.room.Members {
+			status := "not ready"
+			if m.Ready {
+				status = "ready"
+			}
+			lines = append(lines, fmt.Sprintf("%-12s %s", m.Name, status))
+		}
+		for _, bot := range ls.room.Bots {
+			lines = append(lines, fmt.Sprintf("%-12s bot, unless someone joins", bot))
+		}
+		help = []string{
+			"press 'r' to toggle ready; the game starts when everyone is",
+			"press 'l' to leave the room",
+		}
+	case ls.creating != nil:
+		title = "Open a room"
+		lines = []string{
+			fmt.Sprintf("# Players (3-6): %d", ls.creating.Players),
+			fmt.Sprintf("Advisor ('a' to toggle): %s", onOff(ls.creating.Advisor)),
+			fmt.Sprintf("Spectators see hands, delayed ('v' to toggle): %s", onOff(ls.creating.Reveal)),
+		}
+		help = []string{"press Enter to open it, or 'b' to go back"}
+	default:
+		title = "Rooms"
+		for i, room := range ls.rooms {
+			status := fmt.Sprintf("%d/%d seated", len(room.Members), room.Players)
+			if room.Started {
+				status = "playing"
+			}
+			lines = append(lines, fmt.Sprintf("%d. %-20s %-12s %s", i+1, room.Name, status, describeRules(&room)))
+		}
+		if len(lines) == 0 {
+			lines = append(lines, "No rooms yet.")
+		}
+		action := "join"
+		if ls.watching {
+			action = "watch"
+		}
+		help = []string{
+			fmt.Sprintf("press a room's number to %s it ('w' to switch)", action),
+			"press 'c' to open a room, or 'r' to refresh",
+		}
+	}
+	if ls.problem != "" {
+		help = append(help, "", ls.problem)
+	}
+	help = append(help, "press 'q' at any time to quit")
+	display.DrawList(title, lines, help)
+}
+
+func describeRules(room *server.RoomInfo) string {
+	rules := []string{fmt.Sprintf("%d players", room.Players)}
+	if room.Advisor {
+		rules = append(rules, "advisor allowed")
+	}
+	if room.Reveal {
+		rules = append(rules, "hands shown to spectators")
+	}
+	return strings.Join(rules, ", ")
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
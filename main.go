@@ -3,130 +3,190 @@ package main
 import (
 	"context"
 	"fmt"
+	"math/rand/v2"
 	"os"
-	"time"
+	"strings"
 
 	dis "kugo/display"
 	"kugo/game"
 	inp "kugo/input"
 )
 
+// advisorRollouts is how many games the advisor plays out per option. It is
+// enough to separate good moves from bad without keeping the player waiting.
+const advisorRollouts = 200
+
+// MenuMode is what the player chose to do from the main menu.
+type MenuMode int
+
+const (
+	PlayLocal MenuMode = iota
+	HostLobby
+	JoinLobby
+	WatchLocal
+)
+
+// GameOptions holds everything chosen on the main menu.
+type GameOptions struct {
+	Mode       MenuMode
+	NumPlayers int
+	UserName   string
+	// Guests are the other humans sharing the terminal in a hot-seat game.
+	Guests  []string
+	Advisor bool
+	Address string
+}
+
+// advice pairs the advisor's options with the move and seat they were worked
+// out for, so they can be dropped if the game has moved on in the meantime.
+// The state alone won't do, as the game can come back round to the same one.
+type advice struct {
+	move    int
+	seat    int
+	options []game.Option
+}
+
 func GetPlayerName() (string, error) {
-	var name []rune
-	//var cursorPosition int
+	return Prompt("Enter your name: ", "Player")
+}
 
-	fmt.Print("\033[?25h")
-	defer fmt.Print("\033[?25l")
+// Prompt asks the player to type a line, and returns fallback if they leave
+// it empty.
+func Prompt(label, fallback string) (string, error) {
+	fmt.Print("\033[?25h" + inp.PasteOn)
+	defer fmt.Print(inp.PasteOff + "\033[?25l")
 
 	fmt.Print("\033[2J\033[1;1H")
-	fmt.Print("Enter your name: ")
-
-	buf := make([]byte, 1)
+	var line inp.Line
 	for {
-		_, err := os.Stdin.Read(buf)
+		// Draw the line afresh, then step the cursor back over whatever is
+		// after it.
+		before, after := line.Cursor()
+		fmt.Printf("\r%s%s%s\033[K", label, before, after)
+		if width := inp.StringWidth(after); width > 0 {
+			fmt.Printf("\033[%dD", width)
+		}
+		ev, err := inp.Stdin().Next()
 		if err != nil {
 			return "", err
 		}
-
-		switch buf[0] {
-		case '\r', '\n':
-			if len(name) > 0 {
-				return string(name), nil
-			}
-			return "Player", nil // Fallback for empty name
-		case 127, '\b':
-			if len(name) > 0 {
-				name = name[:len(name)-1]
-				fmt.Print("\b \b")
-			}
-		case 27: // skip escape sequences for now
-			os.Stdin.Read(buf) // consumes '['
-			os.Stdin.Read(buf) // consumes the rest
-		default:
-			if buf[0] >= 32 && buf[0] < 127 {
-				name = append(name, rune(buf[0]))
-				fmt.Printf("%c", buf[0])
-			}
+		if !line.Press(ev) {
+			continue
 		}
+		if name := strings.TrimSpace(line.String()); name != "" {
+			return name, nil
+		}
+		return fallback, nil
 	}
 }
 
-func RunMainMenu(chanErr chan error) (int, string, error) {
-	menuChan := make(chan rune)
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				chanErr <- fmt.Errorf("menu input panic: %v", r)
-			}
-		}()
-		var buf = make([]byte, 1)
-		for {
-			_, err := os.Stdin.Read(buf)
-			if err != nil {
-				chanErr <- err
-				return
-			}
-			if rune(buf[0]) == 'q' {
-				chanErr <- fmt.Errorf("User Quit")
-				return
-			}
-			select {
-			case <-ctx.Done():
-				return
-			case menuChan <- rune(buf[0]):
-			}
-		}
-	}()
-
+func RunMainMenu(chanErr chan error) (*GameOptions, error) {
 	display := dis.NewDisplay(chanErr)
+	// The main menu is moved over just as the game's menus are.
+	var menu inp.Navigator = display
 
-	var selection int
 	var confirmed bool
-
-	go func() {
-		for !confirmed {
-			display.DrawMenuScreen(selection)
-			time.Sleep(time.Millisecond * 41)
-		}
-	}()
+	var advisor = false
+	var mode = PlayLocal
+	var players = 3
+	var humans = 1
 
 	for !confirmed {
-		select {
-		case r := <-menuChan:
-			switch r {
-			case '3', '4', '5', '6':
-				selection = int(r - '3') // so it fits 0-3
-			case '\r', '\n':
-				confirmed = true
-			}
-		case err := <-chanErr:
-			return 0, "", err
+		display.DrawMenuScreen("Pick by number, or with the arrows and Enter:", []dis.MenuOption{
+			{Key: 1, Label: "Play"},
+			{Key: 2, Label: fmt.Sprintf("Players: %d", players)},
+			{Key: 3, Label: fmt.Sprintf("Humans at this terminal ('p'): %d", humans)},
+			{Key: 4, Label: fmt.Sprintf("Advisor ('a'): %s", onOff(advisor))},
+			{Key: 5, Label: "Host a lobby for friends ('h')"},
+			{Key: 6, Label: "Join a lobby"},
+			{Key: 7, Label: "Watch the bots play ('w')"},
+		})
+		ev, err := inp.Stdin().Next()
+		if err != nil {
+			return nil, err
+		}
+		key, moved := menu.Navigate(ev.Key)
+		if moved || ev.Alt {
+			continue
 		}
+		switch key {
+		case 'q':
+			return nil, fmt.Errorf("User Quit")
+		case '1':
+			confirmed = true
+		case '2':
+			players = (players-3+1)%4 + 3
+		case '3', 'p':
+			humans = humans%6 + 1
+		case '4', 'a':
+			advisor = !advisor
+		case '5', 'h':
+			mode, confirmed = HostLobby, true
+		case '6':
+			mode, confirmed = JoinLobby, true
+		case '7', 'w':
+			mode, confirmed = WatchLocal, true
+		}
+	}
+	opts := GameOptions{
+		Mode:       mode,
+		NumPlayers: players,
+		Advisor:    advisor,
+	}
+	if mode == WatchLocal {
+		return &opts, nil
 	}
 	userName, err := GetPlayerName()
 	if err != nil {
-		return 0, "", err
+		return nil, err
 	}
-	return selection + 3, userName, nil
+	opts.UserName = userName
+	if mode == PlayLocal {
+		for i := 2; i <= min(humans, opts.NumPlayers); i++ {
+			name, err := Prompt(fmt.Sprintf("Player %d, enter your name: ", i), fmt.Sprintf("Player %d", i))
+			if err != nil {
+				return nil, err
+			}
+			opts.Guests = append(opts.Guests, name)
+		}
+	}
+	if mode == JoinLobby {
+		fallback := fmt.Sprintf("localhost:%d", lobbyPort)
+		opts.Address, err = Prompt(fmt.Sprintf("Lobby address [%s]: ", fallback), fallback)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &opts, nil
 }
 
 func GameLoop() error {
 	// Run the main menu to get number of players
 	var chanErr = make(chan error)
-	numPlayers, userName, err := RunMainMenu(chanErr)
+	opts, err := RunMainMenu(chanErr)
 	if err != nil {
 		return err
 	}
+	switch opts.Mode {
+	case HostLobby:
+		return HostLoop(opts)
+	case JoinLobby:
+		return LobbyLoop("tcp", opts.Address, opts.UserName, nil, false)
+	}
 
 	var players []*game.Player
-	var playerNames = []string{userName}
+	var playerNames []string
+	watching := opts.Mode == WatchLocal
+	if !watching {
+		playerNames = append(playerNames, opts.UserName)
+		playerNames = append(playerNames, opts.Guests...)
+	}
+	numHumans := len(playerNames)
+	hotSeat := numHumans > 1
 
 	// Get player names
 	for _, name := range game.BOT_NAMES {
-		if len(playerNames) >= numPlayers {
+		if len(playerNames) >= opts.NumPlayers {
 			break
 		}
 		playerNames = append(playerNames, name)
@@ -135,7 +195,7 @@ func GameLoop() error {
 	// Create players
 	for i, name := range playerNames {
 		var isHuman, isLocal bool
-		if i == 0 {
+		if i < numHumans {
 			isHuman, isLocal = true, true
 		}
 		p, err := game.NewPlayer(name, i, isHuman, isLocal)
@@ -149,24 +209,74 @@ func GameLoop() error {
 	controller := game.NewController(players)
 	controller.ShuffleAndDeal()
 	inputHandler := inp.NewInputHandler(players, chanErr)
+	// The bots remember the people they have played before, but there's
+	// nothing to learn from a table of bots.
+	if !watching {
+		inputHandler.SetProfiles(loadProfiles())
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Initialize input streams
+	// The arrow keys move a cursor over the menu, so the display has to be
+	// made before the keyboard is read.
+	display := dis.NewDisplay(chanErr)
+	if !watching {
+		inputHandler.SetMenu(display)
+	}
+
+	// Initialize input streams. Players sharing the terminal share its
+	// keyboard too, which hands their keys to whoever is holding it.
+	var seats *inp.HotSeat
+	if hotSeat {
+		seats = inp.NewHotSeat(0)
+		keys := make(chan rune)
+		go inputHandler.CreateHumanInputStream(ctx, keys)
+		go seats.Route(ctx, keys, inputHandler.PlayerChans)
+	}
 	for i, _ := range controller.AllPlayers {
-		if i == 0 {
+		if i == 0 && !watching && !hotSeat {
 			go inputHandler.CreateHumanInputStream(ctx, inputHandler.PlayerChans[i])
 			continue
 		}
+		if i < numHumans {
+			continue
+		}
 		go inputHandler.CreateBotInputStream(ctx, inputHandler.PlayerChans[i], i)
 	}
 
-	// Initialize displays
-	display := dis.NewDisplay(chanErr)
-	dispInit := controller.GetDisplayData()
+	// Initialize displays. With nobody at the table to cheat for, someone
+	// watching the bots can see every hand as it happens.
+	displayData := controller.GetDisplayData
+	if watching {
+		// Only 'q' and 't' mean anything, so throw away the rest.
+		keys := make(chan rune)
+		go inputHandler.CreateHumanInputStream(ctx, keys)
+		go func() {
+			for range keys {
+			}
+		}()
+		displayData = func() *game.DisplayData {
+			return controller.RevealedView().DisplayData()
+		}
+	}
+	// In a hot-seat game only the hand of whoever holds the terminal is shown.
+	seat := func() int { return 0 }
+	if hotSeat {
+		seat = seats.Holder
+		displayData = func() *game.DisplayData {
+			return controller.View(seats.Holder()).DisplayData()
+		}
+	}
+	dispInit := displayData()
 	display.UpdateDisplay(dispInit)
 	go display.DrawDisplay(ctx)
+	hintChan := make(chan advice)
+	var remembered bool
+	var seatChanges <-chan struct{}
+	if hotSeat {
+		seatChanges = seats.Changes()
+	}
 
 	// Start main game loop
 	for {
@@ -181,23 +291,63 @@ func GameLoop() error {
 
 		var gotInput bool
 		for !gotInput {
-			// Update Game
+			if hotSeat {
+				seats.Update(stateData)
+				if next := seats.Waiting(); next >= 0 {
+					display.DrawCurtain(fmt.Sprintf("Pass to %s - press Enter", players[next].Name))
+					display.ClearHint()
+				} else {
+					display.DrawCurtain("")
+				}
+			}
+			if inputHandler.HintRequested() && opts.Advisor && !watching && seat() >= 0 {
+				// The advisor only ever sees the local player's redacted view.
+				view := controller.View(seat())
+				go func() {
+					rng := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+					hint := advice{view.Move, view.Seat, game.Advise(view, advisorRollouts, rng)}
+					select {
+					case hintChan <- hint:
+					case <-ctx.Done():
+					}
+				}()
+			}
+			toDisplays := displayData()
+			display.UpdateDisplay(toDisplays)
+			display.UpdateThoughts(inputHandler.BotThoughts())
+
+			// Wait for something to change before drawing the table again.
 			select {
 			case inputData := <-inputChan:
 				controller.UpdateGame(inputData)
+				display.ClearHint()
 				gotInput = true
+				if controller.Phase == game.EndGame && !watching && !remembered {
+					rememberGame(players, controller.History())
+					remembered = true
+				}
+			case hint := <-hintChan:
+				if hint.move == controller.Moves() && hint.seat == seat() {
+					display.UpdateHint(hint.options)
+				}
+			case <-inputHandler.Changes():
+			case <-seatChanges:
 			case err := <-chanErr:
 				return err
-			default:
-				// just update display
 			}
-			toDisplays := controller.GetDisplayData()
-			display.UpdateDisplay(toDisplays)
 		}
 	}
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		err := RunCommand(os.Args[1], os.Args[2:])
+		if err != nil && err.Error() != "User Quit" {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 	err := dis.WrapDisplay(GameLoop)
 	if err != nil && err.Error() == "User Quit" {
 		os.Exit(0)
@@ -0,0 +1,179 @@
+package mental
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"kugo/game"
+)
+
+// Keyholder is one player's side of the protocol, as a Table deals through
+// it. A Peer is one, and a Keyholder across a network only has to pass the
+// numbers each method takes and returns to the Peer at the far end. The one
+// exception is the card Deal returns, which a Keyholder across a network
+// keeps to itself by returning game.NoCard.
+type Keyholder interface {
+	Shuffle(deck []*big.Int) ([]*big.Int, error)
+	Relock(slots []int, deck []*big.Int) ([]*big.Int, error)
+	Share(slots []int, deck []*big.Int) ([]*big.Int, error)
+	Key(slot int) (*big.Int, error)
+	Deal(slot int, card *big.Int) (game.Card, error)
+}
+
+// Peer is one player's side of the protocol. It holds their keys, which
+// never leave it except one card's at a time through Key, and only for the
+// card on top of the deck or one that has already been dealt.
+type Peer struct {
+	Seat int
+	// shared is the lock the peer put on every card of the deck before a
+	// reshuffle, and shuffle the one it shuffled them under. Both are only
+	// held between passes.
+	shared  *key
+	shuffle *key
+	// cards holds the peer's key for each card, by slot. A slot is a card's
+	// place from one shuffle to the next, and is numbered by the Table.
+	cards map[int]*key
+	// deck is the slots still in the deck, top first, and dealt the slots
+	// that have been drawn from it. hand is what was under the peer's lock
+	// in each slot dealt to it.
+	deck  []int
+	dealt map[int]bool
+	hand  map[int]int
+}
+
+func NewPeer(seat int) *Peer {
+	return &Peer{Seat: seat, cards: map[int]*key{}, dealt: map[int]bool{}, hand: map[int]int{}}
+}
+
+// Share swaps the peer's locks on the cards in the given slots for one key
+// common to them all, so the cards can be shuffled together again.
+func (pr *Peer) Share(slots []int, deck []*big.Int) ([]*big.Int, error) {
+	shared, err := newKey()
+	if err != nil {
+		return nil, err
+	}
+	if len(slots) != len(deck) {
+		return nil, fmt.Errorf("%w: %d slots for %d cards", ErrCheat, len(slots), len(deck))
+	}
+	out := make([]*big.Int, len(deck))
+	for i, c := range deck {
+		k, ok := pr.cards[slots[i]]
+		if !ok {
+			return nil, fmt.Errorf("seat %d has no key for slot %d", pr.Seat, slots[i])
+		}
+		out[i] = power(power(c, k.unlock), shared.lock)
+	}
+	for _, slot := range slots {
+		delete(pr.cards, slot)
+		delete(pr.dealt, slot)
+		delete(pr.hand, slot)
+	}
+	pr.shared, pr.deck = shared, nil
+	return out, nil
+}
+
+// Shuffle locks every card with a new key and shuffles them. It is the first
+// pass of a shuffle.
+func (pr *Peer) Shuffle(deck []*big.Int) ([]*big.Int, error) {
+	shuffle, err := newKey()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*big.Int, len(deck))
+	for i, c := range deck {
+		out[i] = power(c, shuffle.lock)
+	}
+	for i := len(out) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return nil, err
+		}
+		out[i], out[j.Int64()] = out[j.Int64()], out[i]
+	}
+	pr.shuffle = shuffle
+	return out, nil
+}
+
+// Relock swaps the shuffle lock, and the shared one if there is one, for a
+// key of its own on each card, kept under the slot given for it. It is the
+// second pass of a shuffle.
+func (pr *Peer) Relock(slots []int, deck []*big.Int) ([]*big.Int, error) {
+	if pr.shuffle == nil {
+		return nil, fmt.Errorf("seat %d hasn't shuffled", pr.Seat)
+	}
+	if len(slots) != len(deck) {
+		return nil, fmt.Errorf("%w: %d slots for %d cards", ErrCheat, len(slots), len(deck))
+	}
+	unlock := pr.shuffle.unlock
+	if pr.shared != nil {
+		unlock = new(big.Int).Mul(unlock, pr.shared.unlock)
+		unlock.Mod(unlock, order)
+	}
+	out := make([]*big.Int, len(deck))
+	for i, c := range deck {
+		k, err := newKey()
+		if err != nil {
+			return nil, err
+		}
+		out[i] = power(power(c, unlock), k.lock)
+		pr.cards[slots[i]] = k
+	}
+	pr.shared, pr.shuffle = nil, nil
+	pr.deck = slices.Clone(slots)
+	return out, nil
+}
+
+// Key hands over the power that undoes the peer's lock on the card in the
+// given slot, to whoever is meant to see it. That is either the card on top
+// of the deck, which is then dealt, or one that was dealt before and is
+// being shown. Asking for any other would be peeking at the deck.
+func (pr *Peer) Key(slot int) (*big.Int, error) {
+	k, ok := pr.cards[slot]
+	if !ok {
+		return nil, fmt.Errorf("seat %d has no key for slot %d", pr.Seat, slot)
+	}
+	if !pr.dealt[slot] {
+		if len(pr.deck) == 0 || pr.deck[0] != slot {
+			return nil, fmt.Errorf("%w: seat %d was asked to open slot %d, which isn't on top of the deck", ErrCheat, pr.Seat, slot)
+		}
+		pr.deck = pr.deck[1:]
+		pr.dealt[slot] = true
+	}
+	return k.unlock, nil
+}
+
+// Deal takes the peer's own lock off the card on top of the deck, which is
+// being dealt to it with every other player's lock already taken off, and
+// keeps it. Nobody else learns the card until the peer gives up its key for
+// it to be shown.
+func (pr *Peer) Deal(slot int, card *big.Int) (game.Card, error) {
+	k, ok := pr.cards[slot]
+	if !ok {
+		return game.NoCard, fmt.Errorf("seat %d has no key for slot %d", pr.Seat, slot)
+	}
+	if len(pr.deck) == 0 || pr.deck[0] != slot {
+		return game.NoCard, fmt.Errorf("%w: seat %d was dealt slot %d, which isn't on top of the deck", ErrCheat, pr.Seat, slot)
+	}
+	if !between(card, prime) {
+		return game.NoCard, fmt.Errorf("%w: seat %d was dealt no number mod p", ErrCheat, pr.Seat)
+	}
+	n, ok := decode(power(card, k.unlock))
+	if !ok {
+		return game.NoCard, fmt.Errorf("%w: slot %d didn't open to a card, so someone's key was false", ErrCheat, slot)
+	}
+	pr.deck = pr.deck[1:]
+	pr.dealt[slot] = true
+	pr.hand[slot] = n
+	return cardOf(n), nil
+}
+
+// Card is the card the peer was dealt in a slot, if it was.
+func (pr *Peer) Card(slot int) (game.Card, bool) {
+	n, ok := pr.hand[slot]
+	if !ok {
+		return game.NoCard, false
+	}
+	return cardOf(n), true
+}
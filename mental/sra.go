@@ -0,0 +1,124 @@
+// Package mental deals the court deck by the "mental poker" protocol, so that
+// players with no trusted host to hold the deck can still deal, draw and
+// reveal cards without anyone knowing the order of the deck or another
+// player's hand.
+//
+// It uses SRA commutative encryption. A card is a number m modulo a public
+// prime, and a player locks it by raising it to a secret power e, which only
+// they can undo with its inverse d. Locks can be added and taken off in any
+// order. The deck is shuffled in two passes round the table:
+//
+//  1. Each player in turn locks every card with one key of their own and
+//     shuffles the deck.
+//  2. Each player in turn swaps that lock for a separate key per card,
+//     without moving the cards.
+//
+// After that, a card is drawn by every other player handing the drawer their
+// key for it, and revealed by sharing every key for it. Only a few numbers
+// stand for real cards, so a key that doesn't open what it should is caught,
+// and nobody can reveal a card they weren't dealt.
+//
+// Whoever returns a card to the deck knows where it is, so the deck is
+// shuffled again whenever that happens. First each player swaps their locks on
+// the cards left in the deck for one key common to them all, then the two
+// passes are run again.
+//
+// A Table deals through a Keyholder for each player: a Peer in the same
+// process, or one at the other end of a connection, as the server package
+// uses for players who hold their own keys. The Table takes every other
+// player's lock off a card as it is dealt and hands it to the drawer, whose
+// own lock stays on until the card is shown, so whoever runs the Table
+// doesn't see it before then. A Peer only gives up its key for the card on
+// top of the deck or one already dealt. Nobody, the Table included, can pick
+// the order of the deck or look ahead in it, as long as one player shuffles
+// honestly.
+package mental
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+
+	"kugo/game"
+)
+
+// ErrCheat is wrapped by every error that means a player broke the protocol.
+var ErrCheat = errors.New("cheating detected")
+
+// prime is the 2048-bit safe prime of RFC 3526's group 14. A safe prime means
+// p-1 has no small factors for a lock to leak through.
+var prime = func() *big.Int {
+	p, _ := new(big.Int).SetString(strings.Join([]string{
+		"FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD1",
+		"29024E088A67CC74020BBEA63B139B22514A08798E3404DD",
+		"EF9519B3CD3A431B302B0A6DF25F14374FE1356D6D51C245",
+		"E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7ED",
+		"EE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3D",
+		"C2007CB8A163BF0598DA48361C55D39A69163FA8FD24CF5F",
+		"83655D23DCA3AD961C62F356208552BB9ED529077096966D",
+		"670C354E4ABC9804F1746C08CA18217C32905E462E36CE3B",
+		"E39E772C180E86039B2783A2EC07A28FB5C55DF06F4C52C9",
+		"DE2BCBF6955817183995497CEA956AE515D2261898FA0510",
+		"15728E5A8AACAA68FFFFFFFFFFFFFFFF",
+	}, ""), 16)
+	return p
+}()
+
+// order is how many powers there are to lock with, p-1.
+var order = new(big.Int).Sub(prime, big.NewInt(1))
+
+// key is a lock and the power that undoes it.
+type key struct {
+	lock, unlock *big.Int
+}
+
+func newKey() (*key, error) {
+	for {
+		e, err := rand.Int(rand.Reader, order)
+		if err != nil {
+			return nil, err
+		}
+		if e.Cmp(big.NewInt(3)) < 0 {
+			continue
+		}
+		// Only powers coprime to p-1 can be undone.
+		if d := new(big.Int).ModInverse(e, order); d != nil {
+			return &key{lock: e, unlock: d}, nil
+		}
+	}
+}
+
+func power(x, exp *big.Int) *big.Int {
+	return new(big.Int).Exp(x, exp, prime)
+}
+
+// deckSize is how many cards the court deck holds.
+var deckSize = len(game.AllCards) * 3
+
+// encode is the number standing for the nth card of a fresh deck, in which
+// each role appears three times in a row. The numbers are all squares, so
+// whether a locked card is one can't give anything away.
+func encode(n int) *big.Int {
+	m := big.NewInt(int64(n + 2))
+	return m.Mul(m, m)
+}
+
+// decode is the reverse of encode. It reports false for anything that isn't
+// a card.
+func decode(m *big.Int) (int, bool) {
+	root := new(big.Int).Sqrt(m)
+	if new(big.Int).Mul(root, root).Cmp(m) != 0 || !root.IsInt64() {
+		return 0, false
+	}
+	n := int(root.Int64()) - 2
+	if n < 0 || n >= deckSize {
+		return 0, false
+	}
+	return n, true
+}
+
+// cardOf is the role of the nth card of a fresh deck.
+func cardOf(n int) game.Card {
+	return game.AllCards[n/3]
+}
@@ -0,0 +1,258 @@
+package mental
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"kugo/game"
+)
+
+// Table runs the protocol between every player's Keyholder, and deals for a
+// Controller as its game.Dealer. It only ever learns a card by putting
+// together every player's key for it, so it can't see the deck's order, and
+// each Peer only opens the card on top. A card being dealt keeps the
+// drawer's lock, so the Table only learns it when it is shown, unless the
+// drawer's Keyholder shows it straight away.
+type Table struct {
+	Keyholders []Keyholder
+	// slots holds the locked card in each slot, and deck the slots still in
+	// the deck, top first.
+	slots map[int]*big.Int
+	deck  []int
+	// held is who holds the card in each dealt slot, and opened what it was
+	// opened to, if it has been. lost marks the slots that have been turned
+	// face up for good.
+	held   map[int]int
+	opened map[int]int
+	lost   map[int]bool
+	next   int
+}
+
+// NewTable seats a Peer in this process for each player and shuffles a fresh
+// deck between them.
+func NewTable(players int) (*Table, error) {
+	var holders []Keyholder
+	for seat := range players {
+		holders = append(holders, NewPeer(seat))
+	}
+	return Deal(holders)
+}
+
+// Deal shuffles a fresh deck between the players' Keyholders, one for each
+// seat in order, some of which may be at the other end of a network.
+func Deal(holders []Keyholder) (*Table, error) {
+	t := Table{
+		Keyholders: holders,
+		slots:      map[int]*big.Int{},
+		held:       map[int]int{},
+		opened:     map[int]int{},
+		lost:       map[int]bool{},
+	}
+	var deck []*big.Int
+	for n := range deckSize {
+		deck = append(deck, encode(n))
+	}
+	if err := t.shuffle(deck); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// shuffle passes the cards round the table twice, as the package doc
+// describes, and makes the result the deck.
+func (t *Table) shuffle(deck []*big.Int) error {
+	var err error
+	n := len(deck)
+	for _, kh := range t.Keyholders {
+		if deck, err = checked(n)(kh.Shuffle(deck)); err != nil {
+			return err
+		}
+	}
+	slots := make([]int, n)
+	for i := range slots {
+		slots[i] = t.next
+		t.next++
+	}
+	for _, kh := range t.Keyholders {
+		if deck, err = checked(n)(kh.Relock(slots, deck)); err != nil {
+			return err
+		}
+	}
+	t.deck = slots
+	for i, slot := range slots {
+		t.slots[slot] = deck[i]
+	}
+	return nil
+}
+
+// checked passes on a pass's deck, as long as it still has n cards and each
+// of them is a number a card could be locked to.
+func checked(n int) func([]*big.Int, error) ([]*big.Int, error) {
+	return func(deck []*big.Int, err error) ([]*big.Int, error) {
+		if err != nil {
+			return nil, err
+		}
+		if len(deck) != n {
+			return nil, fmt.Errorf("%w: the deck came back with %d cards, not %d", ErrCheat, len(deck), n)
+		}
+		for i, c := range deck {
+			if !between(c, prime) {
+				return nil, fmt.Errorf("%w: card %d of the deck came back as no number mod p", ErrCheat, i)
+			}
+		}
+		return deck, nil
+	}
+}
+
+// between reports whether x is set and lies strictly between 0 and limit.
+func between(x, limit *big.Int) bool {
+	return x != nil && x.Sign() > 0 && x.Cmp(limit) < 0
+}
+
+// unlock takes the lock of every seat but skip off the card in a slot.
+func (t *Table) unlock(slot, skip int) (*big.Int, error) {
+	c := t.slots[slot]
+	for seat, kh := range t.Keyholders {
+		if seat == skip {
+			continue
+		}
+		k, err := kh.Key(slot)
+		if err != nil {
+			return nil, err
+		}
+		if !between(k, order) {
+			return nil, fmt.Errorf("%w: a key for slot %d is no power mod p", ErrCheat, slot)
+		}
+		c = power(c, k)
+	}
+	return c, nil
+}
+
+// open takes every lock off the card in a slot and checks that a card is
+// underneath, and one that isn't already somewhere else.
+func (t *Table) open(slot int) (int, error) {
+	c, err := t.unlock(slot, -1)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := decode(c)
+	if !ok {
+		return 0, fmt.Errorf("%w: slot %d didn't open to a card, so someone's key was false", ErrCheat, slot)
+	}
+	for other, m := range t.opened {
+		if m == n && other != slot {
+			return 0, fmt.Errorf("%w: slot %d opened to a card already dealt", ErrCheat, slot)
+		}
+	}
+	return n, nil
+}
+
+// Draw deals the top card of the deck to the player. Every other player's
+// lock comes off it, and the player's Keyholder takes off its own. The card
+// is dealt face down unless the Keyholder shows what it was.
+func (t *Table) Draw(player int) (game.Card, error) {
+	if len(t.deck) == 0 {
+		return game.NoCard, errors.New("the deck is empty")
+	}
+	if player < 0 || player >= len(t.Keyholders) {
+		return game.NoCard, fmt.Errorf("there is no seat %d at the table", player)
+	}
+	slot := t.deck[0]
+	c, err := t.unlock(slot, player)
+	if err != nil {
+		return game.NoCard, err
+	}
+	card, err := t.Keyholders[player].Deal(slot, c)
+	if err != nil {
+		return game.NoCard, err
+	}
+	t.deck = t.deck[1:]
+	t.held[slot] = player
+	if card == game.NoCard {
+		return game.FaceDown(slot), nil
+	}
+	// A card the Keyholder shows is opened in full to check it, as its key
+	// is no secret from the Table either.
+	n, err := t.open(slot)
+	if err != nil {
+		return game.NoCard, err
+	}
+	if cardOf(n) != card {
+		return game.NoCard, fmt.Errorf("%w: seat %d said slot %d held a %s", ErrCheat, player, slot, card)
+	}
+	t.opened[slot] = n
+	return card, nil
+}
+
+// find is the slot of a card the player holds, either face down or as the
+// card it was opened to.
+func (t *Table) find(player int, card game.Card) (int, error) {
+	if slot, ok := card.FaceDown(); ok {
+		if holder, dealt := t.held[slot]; dealt && holder == player && !t.lost[slot] {
+			return slot, nil
+		}
+	}
+	for slot, holder := range t.held {
+		n, opened := t.opened[slot]
+		if holder == player && !t.lost[slot] && opened && cardOf(n) == card {
+			return slot, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: seat %d doesn't hold a %s", ErrCheat, player, card)
+}
+
+// Reveal checks that the player really holds the card they have shown
+// everyone, by opening it in public, and returns what it is.
+func (t *Table) Reveal(player int, card game.Card) (game.Card, error) {
+	slot, err := t.find(player, card)
+	if err != nil {
+		return game.NoCard, err
+	}
+	n, err := t.open(slot)
+	if err != nil {
+		return game.NoCard, err
+	}
+	if opened, ok := t.opened[slot]; ok && n != opened {
+		return game.NoCard, fmt.Errorf("%w: slot %d opened differently in public", ErrCheat, slot)
+	}
+	t.opened[slot] = n
+	return cardOf(n), nil
+}
+
+// Lose reveals the card and leaves it face up for the rest of the game.
+func (t *Table) Lose(player int, card game.Card) (game.Card, error) {
+	slot, err := t.find(player, card)
+	if err != nil {
+		return game.NoCard, err
+	}
+	shown, err := t.Reveal(player, card)
+	if err != nil {
+		return game.NoCard, err
+	}
+	t.lost[slot] = true
+	return shown, nil
+}
+
+// Return puts a card the player holds back in the deck, and shuffles it.
+func (t *Table) Return(player int, card game.Card) error {
+	slot, err := t.find(player, card)
+	if err != nil {
+		return err
+	}
+	delete(t.held, slot)
+	delete(t.opened, slot)
+	slots := append(slices.Clone(t.deck), slot)
+	deck := make([]*big.Int, len(slots))
+	for i, s := range slots {
+		deck[i] = t.slots[s]
+		delete(t.slots, s)
+	}
+	for _, kh := range t.Keyholders {
+		if deck, err = checked(len(slots))(kh.Share(slots, deck)); err != nil {
+			return err
+		}
+	}
+	return t.shuffle(deck)
+}
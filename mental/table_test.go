@@ -0,0 +1,180 @@
+package mental
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"kugo/game"
+)
+
+func TestTableDealsWholeDeck(t *testing.T) {
+	table, err := NewTable(3)
+	if err != nil {
+		t.Fatalf("NewTable: %v", err)
+	}
+	counts := map[game.Card]int{}
+	for i := range deckSize {
+		card, err := table.Draw(i % 3)
+		if err != nil {
+			t.Fatalf("Draw: %v", err)
+		}
+		counts[card]++
+	}
+	for _, card := range game.AllCards {
+		if counts[card] != 3 {
+			t.Errorf("dealt %d %ss, want 3", counts[card], card)
+		}
+	}
+	if _, err := table.Draw(0); err == nil {
+		t.Errorf("drew from an empty deck")
+	}
+}
+
+func TestTableReturnAndReveal(t *testing.T) {
+	table, err := NewTable(3)
+	if err != nil {
+		t.Fatalf("NewTable: %v", err)
+	}
+	card, err := table.Draw(1)
+	if err != nil {
+		t.Fatalf("Draw: %v", err)
+	}
+	if _, err := table.Reveal(1, card); err != nil {
+		t.Errorf("Reveal of a held card: %v", err)
+	}
+	if _, err := table.Reveal(2, card); !errors.Is(err, ErrCheat) {
+		t.Errorf("Reveal by a player without the card: got %v, want ErrCheat", err)
+	}
+	if err := table.Return(1, card); err != nil {
+		t.Fatalf("Return: %v", err)
+	}
+	if len(table.deck) != deckSize {
+		t.Errorf("deck has %d cards after a return, want %d", len(table.deck), deckSize)
+	}
+	if _, err := table.Reveal(1, card); !errors.Is(err, ErrCheat) {
+		t.Errorf("Reveal of a returned card: got %v, want ErrCheat", err)
+	}
+
+	card, _ = table.Draw(0)
+	if _, err := table.Lose(0, card); err != nil {
+		t.Fatalf("Lose: %v", err)
+	}
+	if _, err := table.Reveal(0, card); !errors.Is(err, ErrCheat) {
+		t.Errorf("Reveal of a lost card: got %v, want ErrCheat", err)
+	}
+}
+
+func TestTableCatchesFalseKey(t *testing.T) {
+	table, err := NewTable(3)
+	if err != nil {
+		t.Fatalf("NewTable: %v", err)
+	}
+	// A peer swaps its key for the top card for one that opens nothing.
+	slot := table.deck[0]
+	bogus, _ := newKey()
+	table.Keyholders[2].(*Peer).cards[slot] = &key{lock: bogus.lock, unlock: big.NewInt(3)}
+	if _, err := table.Draw(0); !errors.Is(err, ErrCheat) {
+		t.Errorf("Draw with a false key: got %v, want ErrCheat", err)
+	}
+}
+
+func TestControllerDealsFromTable(t *testing.T) {
+	var players []*game.Player
+	for i, name := range []string{"Ann", "Ben", "Cal"} {
+		p, _ := game.NewPlayer(name, i, false, false)
+		players = append(players, p)
+	}
+	table, err := NewTable(len(players))
+	if err != nil {
+		t.Fatalf("NewTable: %v", err)
+	}
+	c := game.NewController(players)
+	c.SetDealer(table)
+	c.ShuffleAndDeal()
+	if err := c.DealerErr(); err != nil {
+		t.Fatalf("dealing: %v", err)
+	}
+	for _, p := range players {
+		if len(p.CardsHeld) != 2 {
+			t.Errorf("%s was dealt %d cards, want 2", p.Name, len(p.CardsHeld))
+		}
+		for _, card := range p.CardsHeld {
+			if _, err := table.Reveal(p.Index, card); err != nil {
+				t.Errorf("%s can't reveal their %s: %v", p.Name, card, err)
+			}
+		}
+	}
+}
+
+func TestPeerOpensOnlyTheTopCard(t *testing.T) {
+	table, err := NewTable(3)
+	if err != nil {
+		t.Fatalf("NewTable: %v", err)
+	}
+	peer := table.Keyholders[1]
+	if _, err := peer.Key(table.deck[1]); !errors.Is(err, ErrCheat) {
+		t.Errorf("Key for the second card down: got %v, want ErrCheat", err)
+	}
+	card, err := table.Draw(0)
+	if err != nil {
+		t.Fatalf("Draw: %v", err)
+	}
+	if _, err := table.Reveal(0, card); err != nil {
+		t.Errorf("Reveal of a dealt card: %v", err)
+	}
+}
+
+// remotePeer stands in for a Peer at the far end of a connection, which keeps
+// the cards it is dealt to itself.
+type remotePeer struct {
+	*Peer
+}
+
+func (rp remotePeer) Deal(slot int, card *big.Int) (game.Card, error) {
+	_, err := rp.Peer.Deal(slot, card)
+	return game.NoCard, err
+}
+
+func TestTableDealsRemoteCardsFaceDown(t *testing.T) {
+	remote := NewPeer(1)
+	table, err := Deal([]Keyholder{NewPeer(0), remotePeer{remote}, NewPeer(2)})
+	if err != nil {
+		t.Fatalf("Deal: %v", err)
+	}
+	slot := table.deck[0]
+	card, err := table.Draw(1)
+	if err != nil {
+		t.Fatalf("Draw: %v", err)
+	}
+	if n, ok := card.FaceDown(); !ok || n != slot {
+		t.Fatalf("dealt %v to a remote peer, want slot %d face down", card, slot)
+	}
+	dealt, ok := remote.Card(slot)
+	if !ok {
+		t.Fatalf("the remote peer wasn't dealt slot %d", slot)
+	}
+
+	// The Table has every key but the remote peer's, and that isn't enough.
+	c, err := table.unlock(slot, 1)
+	if err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	if _, ok := decode(c); ok {
+		t.Errorf("the table opened a card dealt to a remote peer")
+	}
+	if _, ok := table.opened[slot]; ok {
+		t.Errorf("the table knows the card in slot %d before it is shown", slot)
+	}
+
+	shown, err := table.Reveal(1, card)
+	if err != nil {
+		t.Fatalf("Reveal: %v", err)
+	}
+	if shown != dealt {
+		t.Errorf("revealed %v, but the remote peer was dealt %v", shown, dealt)
+	}
+	if _, err := table.Lose(1, shown); err != nil {
+		t.Errorf("Lose of the card once shown: %v", err)
+	}
+}
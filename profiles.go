@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"kugo/game"
+)
+
+// profilesPath is where the bots keep what they have seen of players in
+// earlier local games.
+func profilesPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "kugo", "profiles.json"), nil
+}
+
+// loadProfiles reads the bots' record of earlier games. It is empty, not an
+// error, if there isn't one yet or it can't be read.
+func loadProfiles() game.ProfileBook {
+	book := game.ProfileBook{}
+	path, err := profilesPath()
+	if err != nil {
+		game.Debugf("finding the profiles: %v", err)
+		return book
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return book
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &book)
+	}
+	if err != nil {
+		game.Debugf("reading %s: %v", path, err)
+		return game.ProfileBook{}
+	}
+	return book
+}
+
+// rememberGame adds a finished game to the bots' record, for them to go on in
+// the next one.
+func rememberGame(players []*game.Player, history []game.Event) {
+	book := loadProfiles()
+	book.Remember(players, history)
+	path, err := profilesPath()
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0o755)
+	}
+	var data []byte
+	if err == nil {
+		data, err = json.Marshal(book)
+	}
+	if err == nil {
+		err = os.WriteFile(path, data, 0o644)
+	}
+	if err != nil {
+		game.Debugf("saving the profiles: %v", err)
+	}
+}
@@ -0,0 +1,384 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	dis "kugo/display"
+	"kugo/game"
+)
+
+// DefaultPoll is how long a request for events waits for one if none is given.
+const DefaultPoll = 30 * time.Second
+
+// maxPoll caps how long a request for events can ask to wait.
+const maxPoll = 2 * time.Minute
+
+// DefaultKeep is how long a game that is over is kept for its seats to read
+// how it ended, before it is forgotten.
+const DefaultKeep = 10 * time.Minute
+
+// API hosts any number of games for programs rather than people, over plain
+// JSON and HTTP:
+//
+//	POST   /games              create a game from a GameRequest
+//	GET    /games/{id}/view    the seat's latest view and the moves open to it
+//	POST   /games/{id}/moves   press a key for the seat, as {"key":"1"}
+//	GET    /games/{id}/events  long-poll for the seat's messages, see below
+//	DELETE /games/{id}         stop the game and forget it
+//
+// Every request but the first is made for a seat, by sending the token the
+// seat was given when the game was created as "Authorization: Bearer ...".
+//
+// Each seat's messages, the same ones a TCP client is sent, are numbered from
+// zero. GET /games/{id}/events?after=N&wait=10s answers with every message
+// from N on as soon as there is one, or an empty list once wait is up. Its
+// "next" field is the N to ask for next time, and "over" says the game has
+// ended and no more are coming.
+//
+// Moves go through Press, so they are checked exactly as a terminal player's
+// keys are. A key the game isn't asking for is accepted but does nothing.
+//
+// A game that is over is forgotten Keep after it ends, as if it had been
+// deleted.
+type API struct {
+	Keep  time.Duration
+	ctx   context.Context
+	mu    sync.Mutex
+	games map[string]*apiGame
+}
+
+type apiGame struct {
+	server *Server
+	seats  map[string]*apiSeat
+	cancel context.CancelFunc
+}
+
+// GameRequest is what a game is created from: the rules it is played by, and
+// the names of the humans in it, who take the first seats in order. Bots fill
+// the rest. The API has no advisor, so a game can't be asked for with one.
+type GameRequest struct {
+	Rules  RoomInfo `json:"rules"`
+	Humans []string `json:"humans"`
+}
+
+// GameCreated answers a GameRequest with the game's ID and each human's seat.
+type GameCreated struct {
+	ID    string      `json:"id"`
+	Seats []SeatToken `json:"seats"`
+}
+
+// SeatToken is a human's seat and the token that plays it.
+type SeatToken struct {
+	Seat  int    `json:"seat"`
+	Name  string `json:"name"`
+	Token string `json:"token"`
+}
+
+// SeatView is a seat's latest view, and the moves it can make from it. View
+// is null until the game has started.
+type SeatView struct {
+	Events  int          `json:"events"`
+	View    *game.View   `json:"view"`
+	Options []MoveOption `json:"options"`
+}
+
+// MoveOption is a move open to a seat and the key that makes it.
+type MoveOption struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+}
+
+// SeatEvents answers a long poll for a seat's messages.
+type SeatEvents struct {
+	Events []*Message `json:"events"`
+	Next   int        `json:"next"`
+	Over   bool       `json:"over"`
+}
+
+// NewAPI creates an API whose games run until ctx is done, if they don't end
+// first.
+func NewAPI(ctx context.Context) *API {
+	a := API{Keep: DefaultKeep, ctx: ctx, games: make(map[string]*apiGame)}
+	return &a
+}
+
+// Serve answers requests on ln until ctx is done, then closes ln.
+func (a *API) Serve(ctx context.Context, ln net.Listener) error {
+	hs := newHTTPServer(a.Handler())
+	go func() {
+		<-ctx.Done()
+		hs.Close()
+	}()
+	if err := hs.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return ctx.Err()
+}
+
+// Handler routes the API's requests.
+func (a *API) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /games", a.handleCreate)
+	mux.HandleFunc("GET /games/{id}/view", a.handleView)
+	mux.HandleFunc("POST /games/{id}/moves", a.handleMove)
+	mux.HandleFunc("GET /games/{id}/events", a.handleEvents)
+	mux.HandleFunc("DELETE /games/{id}", a.handleDelete)
+	return mux
+}
+
+// apiError answers a request with an error, in the same shape as the
+// protocol's error messages.
+func apiError(w http.ResponseWriter, code int, format string, args ...any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(&Message{Type: MsgError, Error: fmt.Sprintf(format, args...)})
+}
+
+func apiReply(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (a *API) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req GameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apiError(w, http.StatusBadRequest, "bad game request: %v", err)
+		return
+	}
+	created, err := a.create(&req)
+	if err != nil {
+		apiError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	apiReply(w, http.StatusCreated, created)
+}
+
+// create starts a game with every human already seated.
+func (a *API) create(req *GameRequest) (*GameCreated, error) {
+	if req.Rules.Advisor {
+		return nil, errors.New("the API has no advisor to give hints")
+	}
+	srv, err := New(req.Rules.Players, len(req.Humans))
+	if err != nil {
+		return nil, err
+	}
+	srv.Reveal = req.Rules.Reveal
+	srv.Room = &RoomInfo{Name: req.Rules.Name, Players: req.Rules.Players, Reveal: req.Rules.Reveal}
+	g := apiGame{server: srv, seats: make(map[string]*apiSeat)}
+	created := GameCreated{ID: rand.Text()}
+	for _, name := range req.Humans {
+		st := newAPISeat()
+		idx, err := srv.Join(name, st)
+		if err != nil {
+			return nil, err
+		}
+		// The welcome Join sent carries the seat's token.
+		token := st.events[0].Token
+		g.seats[token] = st
+		st.seat = idx
+		created.Seats = append(created.Seats, SeatToken{Seat: idx, Name: name, Token: token})
+	}
+	ctx, cancel := context.WithCancel(a.ctx)
+	g.cancel = cancel
+	a.mu.Lock()
+	a.games[created.ID] = &g
+	a.mu.Unlock()
+	go func() {
+		if err := srv.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			game.Debugf("api game %s: %v", created.ID, err)
+		}
+		cancel()
+		time.AfterFunc(a.Keep, func() { a.forget(created.ID, &g) })
+	}()
+	return &created, nil
+}
+
+// forget drops a game, unless it has already gone.
+func (a *API) forget(id string, g *apiGame) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.games[id] == g {
+		delete(a.games, id)
+	}
+}
+
+// seatFor finds the game a request is for, and the seat its token plays. It
+// answers the request itself if either can't be found.
+func (a *API) seatFor(w http.ResponseWriter, r *http.Request) (*apiGame, *apiSeat, bool) {
+	a.mu.Lock()
+	g := a.games[r.PathValue("id")]
+	a.mu.Unlock()
+	if g == nil {
+		apiError(w, http.StatusNotFound, "no game %q", r.PathValue("id"))
+		return nil, nil, false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	st := g.seats[token]
+	if !ok || st == nil {
+		apiError(w, http.StatusUnauthorized, "no seat in this game has that token")
+		return nil, nil, false
+	}
+	return g, st, true
+}
+
+func (a *API) handleView(w http.ResponseWriter, r *http.Request) {
+	_, st, ok := a.seatFor(w, r)
+	if !ok {
+		return
+	}
+	apiReply(w, http.StatusOK, st.seatView())
+}
+
+func (a *API) handleMove(w http.ResponseWriter, r *http.Request) {
+	g, st, ok := a.seatFor(w, r)
+	if !ok {
+		return
+	}
+	var msg Message
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		apiError(w, http.StatusBadRequest, "bad move: %v", err)
+		return
+	}
+	keys := []rune(msg.Key)
+	if len(keys) != 1 || keys[0] < '0' || keys[0] > '9' {
+		apiError(w, http.StatusBadRequest, "key %q is not a single digit", msg.Key)
+		return
+	}
+	if err := g.server.Press(r.Context(), st.seat, keys[0]); err != nil {
+		apiError(w, http.StatusConflict, "%v", err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (a *API) handleEvents(w http.ResponseWriter, r *http.Request) {
+	_, st, ok := a.seatFor(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	after, wait := 0, DefaultPoll
+	var err error
+	if s := q.Get("after"); s != "" {
+		if after, err = strconv.Atoi(s); err != nil || after < 0 {
+			apiError(w, http.StatusBadRequest, "after must be a message number, not %q", s)
+			return
+		}
+	}
+	if s := q.Get("wait"); s != "" {
+		if wait, err = time.ParseDuration(s); err != nil || wait < 0 {
+			apiError(w, http.StatusBadRequest, "wait must be a duration such as 10s, not %q", s)
+			return
+		}
+	}
+	wait = min(wait, maxPoll)
+	// A long poll outlasts the server's write timeout, so it is given
+	// longer.
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(wait + writeTimeout))
+	ctx, cancel := context.WithTimeout(r.Context(), wait)
+	defer cancel()
+	apiReply(w, http.StatusOK, st.poll(ctx, after))
+}
+
+func (a *API) handleDelete(w http.ResponseWriter, r *http.Request) {
+	g, _, ok := a.seatFor(w, r)
+	if !ok {
+		return
+	}
+	g.cancel()
+	a.mu.Lock()
+	delete(a.games, r.PathValue("id"))
+	a.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apiSeat keeps every message sent to a seat, for it to be polled for.
+type apiSeat struct {
+	seat    int
+	mu      sync.Mutex
+	events  []*Message
+	view    *game.View
+	closed  bool
+	changed chan struct{}
+}
+
+func newAPISeat() *apiSeat {
+	return &apiSeat{changed: make(chan struct{})}
+}
+
+// wake lets every poll waiting on the seat know something has happened. The
+// caller must hold st.mu.
+func (st *apiSeat) wake() {
+	close(st.changed)
+	st.changed = make(chan struct{})
+}
+
+func (st *apiSeat) Send(msg *Message) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.closed {
+		return errors.New("game is over")
+	}
+	st.events = append(st.events, msg)
+	if msg.View != nil {
+		st.view = msg.View
+	}
+	st.wake()
+	return nil
+}
+
+func (st *apiSeat) Close() error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if !st.closed {
+		st.closed = true
+		st.wake()
+	}
+	return nil
+}
+
+func (st *apiSeat) seatView() *SeatView {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	sv := SeatView{Events: len(st.events), View: st.view, Options: []MoveOption{}}
+	if st.view != nil {
+		for _, o := range game.LegalOptions(st.view) {
+			sv.Options = append(sv.Options, MoveOption{Key: strconv.Itoa(o.Key), Label: dis.StripColor(o.Label)})
+		}
+	}
+	return &sv
+}
+
+// poll waits until there are messages from after on, the game is over or ctx
+// is done, and returns whatever there is by then.
+func (st *apiSeat) poll(ctx context.Context, after int) *SeatEvents {
+	for {
+		st.mu.Lock()
+		if after < len(st.events) || st.closed {
+			events := SeatEvents{Events: []*Message{}, Next: max(after, len(st.events)), Over: st.closed}
+			if after < len(st.events) {
+				events.Events = append(events.Events, st.events[after:]...)
+			}
+			st.mu.Unlock()
+			return &events
+		}
+		changed := st.changed
+		st.mu.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return &SeatEvents{Events: []*Message{}, Next: after}
+		}
+	}
+}
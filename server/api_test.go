@@ -0,0 +1,155 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"kugo/game"
+)
+
+// apiClient makes requests of a test API for one seat.
+type apiClient struct {
+	base  string
+	token string
+}
+
+func (ac *apiClient) do(t *testing.T, method, path string, body, reply any) int {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req, err := http.NewRequest(method, ac.base+path, &buf)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	if ac.token != "" {
+		req.Header.Set("Authorization", "Bearer "+ac.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	if reply != nil && resp.StatusCode < 300 {
+		if err := json.NewDecoder(resp.Body).Decode(reply); err != nil {
+			t.Fatalf("%s %s: decode: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func startTestAPI(t *testing.T) string {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	ts := httptest.NewServer(NewAPI(ctx).Handler())
+	t.Cleanup(ts.Close)
+	return ts.URL
+}
+
+func TestAPIPlaysIncome(t *testing.T) {
+	base := startTestAPI(t)
+	host := apiClient{base: base}
+	var created GameCreated
+	req := GameRequest{Rules: RoomInfo{Players: 3}, Humans: []string{"Ann", "Ben", "Cat"}}
+	if code := host.do(t, "POST", "/games", &req, &created); code != http.StatusCreated {
+		t.Fatalf("create: got %d, want %d", code, http.StatusCreated)
+	}
+	if len(created.Seats) != 3 {
+		t.Fatalf("created %d seats, want 3", len(created.Seats))
+	}
+	ann := apiClient{base: base, token: created.Seats[0].Token}
+	ben := apiClient{base: base, token: created.Seats[1].Token}
+	path := "/games/" + created.ID
+
+	var first SeatEvents
+	ann.do(t, "GET", path+"/events?after=1&wait=5s", nil, &first)
+	if len(first.Events) == 0 || first.Events[0].Type != MsgView {
+		t.Fatalf("no view once the game started: %+v", first)
+	}
+	var view SeatView
+	ann.do(t, "GET", path+"/view", nil, &view)
+	if len(view.Options) != 5 {
+		t.Errorf("Ann can make %d moves, want 5", len(view.Options))
+	}
+	ben.do(t, "GET", path+"/view", nil, &view)
+	if len(view.Options) != 0 {
+		t.Errorf("Ben can make %d moves out of turn, want 0", len(view.Options))
+	}
+
+	if code := (&apiClient{base: base, token: "wrong"}).do(t, "GET", path+"/view", nil, nil); code != http.StatusUnauthorized {
+		t.Errorf("view with a bad token: got %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := ann.do(t, "GET", "/games/nope/view", nil, nil); code != http.StatusNotFound {
+		t.Errorf("view of an unknown game: got %d, want %d", code, http.StatusNotFound)
+	}
+	if code := ann.do(t, "POST", path+"/moves", &Message{Key: "x"}, nil); code != http.StatusBadRequest {
+		t.Errorf("move with a bad key: got %d, want %d", code, http.StatusBadRequest)
+	}
+	if code := ann.do(t, "POST", path+"/moves", &Message{Key: "1"}, nil); code != http.StatusAccepted {
+		t.Fatalf("move: got %d, want %d", code, http.StatusAccepted)
+	}
+
+	next := view.Events
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		var polled SeatEvents
+		ben.do(t, "GET", path+"/events?after="+strconv.Itoa(next)+"&wait=1s", nil, &polled)
+		next = polled.Next
+		for _, msg := range polled.Events {
+			if msg.View != nil && msg.View.Players[0].Coins == 3 && msg.View.State.Phase == game.SelectAction {
+				if code := ann.do(t, "DELETE", path, nil, nil); code != http.StatusNoContent {
+					t.Errorf("delete: got %d, want %d", code, http.StatusNoContent)
+				}
+				if code := ann.do(t, "GET", path+"/view", nil, nil); code != http.StatusNotFound {
+					t.Errorf("view of a deleted game: got %d, want %d", code, http.StatusNotFound)
+				}
+				return
+			}
+		}
+	}
+	t.Fatalf("Ben never saw Ann take income")
+}
+
+func TestAPIRefusesAdvisor(t *testing.T) {
+	host := apiClient{base: startTestAPI(t)}
+	req := GameRequest{Rules: RoomInfo{Players: 3, Advisor: true}, Humans: []string{"Ann"}}
+	if code := host.do(t, "POST", "/games", &req, nil); code != http.StatusBadRequest {
+		t.Errorf("create with an advisor: got %d, want %d", code, http.StatusBadRequest)
+	}
+}
+
+func TestAPIForgetsFinishedGames(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a := NewAPI(ctx)
+	a.Keep = 10 * time.Millisecond
+	created, err := a.create(&GameRequest{Rules: RoomInfo{Players: 3}, Humans: []string{"Ann"}})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	a.mu.Lock()
+	g := a.games[created.ID]
+	a.mu.Unlock()
+	// However it ends, the game is over once Run returns.
+	g.cancel()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		a.mu.Lock()
+		_, kept := a.games[created.ID]
+		a.mu.Unlock()
+		if !kept {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("the game was still kept long after it ended")
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"kugo/game"
+)
+
+// DiscoveryPort is the UDP port games are announced on.
+const DiscoveryPort = 7778
+
+// BroadcastAddr reaches every machine on the local network.
+var BroadcastAddr = fmt.Sprintf("255.255.255.255:%d", DiscoveryPort)
+
+// announceEvery is how often a host tells the network about its game. Anyone
+// looking needs to listen for at least this long to hear from everyone.
+const announceEvery = time.Second
+
+// Announcement is what a host broadcasts about its game, so players on the
+// same network can find it without being told the address.
+type Announcement struct {
+	// Addr is where to connect to the game. Hosts only send their Port, and
+	// Discover fills in the address the announcement came from.
+	Addr  string     `json:"addr,omitempty"`
+	Port  int        `json:"port"`
+	Lobby bool       `json:"lobby,omitempty"`
+	Rooms []RoomInfo `json:"rooms"`
+}
+
+// Announce sends what describe returns to target every announceEvery until
+// ctx is done. The target is usually BroadcastAddr, but can be any UDP
+// address. A failed send is only logged, as the network may come and go.
+func Announce(ctx context.Context, target string, describe func() Announcement) error {
+	conn, err := net.Dial("udp", target)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	ticker := time.NewTicker(announceEvery)
+	defer ticker.Stop()
+	for {
+		data, err := json.Marshal(describe())
+		if err != nil {
+			return err
+		}
+		if _, err := conn.Write(data); err != nil {
+			game.Debugf("announcing to %s: %v", target, err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Discover collects the announcements that arrive on conn over the given
+// time, keeping the latest from each game, and returns them sorted by
+// address. Anything on conn that isn't an announcement is ignored.
+func Discover(ctx context.Context, conn net.PacketConn, wait time.Duration) ([]Announcement, error) {
+	conn.SetReadDeadline(time.Now().Add(wait))
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	found := map[string]Announcement{}
+	buf := make([]byte, 64<<10)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var ann Announcement
+		if err := json.Unmarshal(buf[:n], &ann); err != nil || ann.Port <= 0 {
+			continue
+		}
+		host, _, err := net.SplitHostPort(from.String())
+		if err != nil {
+			continue
+		}
+		ann.Addr = net.JoinHostPort(host, strconv.Itoa(ann.Port))
+		// Anyone on the network can announce anything, so the names are
+		// cleaned before they get anywhere near a screen.
+		for i := range ann.Rooms {
+			ann.Rooms[i].clean()
+		}
+		found[ann.Addr] = ann
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var games []Announcement
+	for _, ann := range found {
+		games = append(games, ann)
+	}
+	slices.SortFunc(games, func(a, b Announcement) int {
+		return strings.Compare(a.Addr, b.Addr)
+	})
+	return games, nil
+}
+
+// Info describes the game for announcing it, in the same terms as a lobby
+// room. Seats for bots are listed as Bots, so the open seats are whatever is
+// left over.
+func (s *Server) Info() RoomInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info := RoomInfo{Advisor: true, Reveal: s.Reveal}
+	if s.Room != nil {
+		info = *s.Room
+	}
+	info.Players = s.NumPlayers
+	info.Members = nil
+	for _, st := range s.seats {
+		info.Members = append(info.Members, Member{Name: st.name, Ready: true})
+	}
+	// The bots are only named once the players are known, so these are
+	// just to count them by.
+	info.Bots = slices.Clone(game.BOT_NAMES[:s.NumPlayers-s.NumHumans])
+	select {
+	case <-s.started:
+		info.Started = true
+	default:
+	}
+	return info
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// nopSink is a seated player who never reads anything.
+type nopSink struct{}
+
+func (nopSink) Send(*Message) error { return nil }
+func (nopSink) Close() error        { return nil }
+
+func TestDiscoverHearsAnnouncement(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer conn.Close()
+
+	s, _ := New(4, 2)
+	s.Room = &RoomInfo{Name: "Friday"}
+	if _, err := s.Join("Ann", nopSink{}); err != nil {
+		t.Fatalf("Join: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go Announce(ctx, conn.LocalAddr().String(), func() Announcement {
+		return Announcement{Port: 7777, Rooms: []RoomInfo{s.Info()}}
+	})
+
+	games, err := Discover(ctx, conn, 3*announceEvery/2)
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	if len(games) != 1 {
+		t.Fatalf("found %d games, want 1", len(games))
+	}
+	if games[0].Addr != "127.0.0.1:7777" {
+		t.Errorf("game at %s, want 127.0.0.1:7777", games[0].Addr)
+	}
+	room := games[0].Rooms[0]
+	if room.Name != "Friday" || room.Players != 4 || len(room.Members) != 1 || len(room.Bots) != 2 {
+		t.Errorf("got room %+v, want Friday with Ann, 2 bots and a seat open", room)
+	}
+}
+
+func TestDiscoverStopsWithContext(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := Discover(ctx, conn, time.Minute); err == nil {
+		t.Errorf("Discover outlived its context")
+	}
+}
+
+func TestDiscoverCleansNames(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go Announce(ctx, conn.LocalAddr().String(), func() Announcement {
+		return Announcement{Port: 7777, Rooms: []RoomInfo{{
+			Name:    "\x1b]0;owned\x07Friday",
+			Members: []Member{{Name: "Ann\x1b[2J"}},
+			Bots:    []string{"\x1b[HBot"},
+		}}}
+	})
+
+	games, err := Discover(ctx, conn, 3*announceEvery/2)
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	if len(games) != 1 {
+		t.Fatalf("found %d games, want 1", len(games))
+	}
+	room := games[0].Rooms[0]
+	if room.Name != "]0;ownedFriday" || room.Members[0].Name != "Ann[2J" || room.Bots[0] != "[HBot" {
+		t.Errorf("got room %+v, want its names without control characters", room)
+	}
+}
@@ -0,0 +1,472 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+
+	"kugo/game"
+)
+
+// Lobby hosts any number of rooms, each of which becomes a game of its own
+// once everyone sitting in it is ready. Players can see, open, join and leave
+// rooms before their game starts.
+type Lobby struct {
+	mu      sync.Mutex
+	rooms   []*room
+	members map[*member]bool
+	outbox  []delivery
+	sending sync.Mutex
+}
+
+// delivery is a message waiting for l.mu to be released before it is sent.
+type delivery struct {
+	to  *member
+	msg *Message
+}
+
+type room struct {
+	info    RoomInfo
+	members []*member
+	server  *Server
+}
+
+// member is one connection to the lobby. While a game is on, server and seat
+// say where its moves go. watching is the game they are spectating, if any.
+type member struct {
+	*client
+	name     string
+	ready    bool
+	room     *room
+	server   *Server
+	seat     int
+	watching *Server
+}
+
+func NewLobby() *Lobby {
+	l := Lobby{members: make(map[*member]bool)}
+	return &l
+}
+
+// Serve accepts clients on ln until ctx is done, then closes ln.
+func (l *Lobby) Serve(ctx context.Context, ln net.Listener) error {
+	accept(ctx, ln, l.handle)
+	return ctx.Err()
+}
+
+// Rooms lists the rooms that are open or playing.
+func (l *Lobby) Rooms() []RoomInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.roomInfos()
+}
+
+func (l *Lobby) handle(ctx context.Context, conn net.Conn) {
+	m := member{client: newClient(conn)}
+	defer m.Close()
+	var hello Message
+	err := m.dec.Decode(&hello)
+	m.name = cleanName(hello.Name)
+	if err != nil || hello.Type != MsgHello || (m.name == "" && hello.Token == "") {
+		m.sendError("expected a hello message with a name")
+		return
+	}
+	if err := m.greet(&hello); err != nil {
+		m.sendError("%v", err)
+		return
+	}
+
+	if hello.Token != "" {
+		if err := l.rejoin(&m, hello.Token); err != nil {
+			m.sendError("%v", err)
+			return
+		}
+	}
+	l.mu.Lock()
+	l.members[&m] = true
+	if m.server == nil {
+		l.post(&m, &Message{Type: MsgRooms, Rooms: l.roomInfos()})
+	}
+	l.unlock()
+	defer l.drop(&m)
+
+	for {
+		var msg Message
+		if err := m.dec.Decode(&msg); err != nil {
+			l.mu.Lock()
+			srv, seat, watching := m.server, m.seat, m.watching
+			l.mu.Unlock()
+			if srv != nil {
+				srv.Leave(seat, m.client, err)
+			}
+			if watching != nil {
+				watching.Unwatch(m.client)
+			}
+			return
+		}
+		if err := l.dispatch(ctx, &m, &msg); err != nil {
+			m.sendError("%v", err)
+		}
+	}
+}
+
+// rejoin puts a member back in the game they dropped out of.
+func (l *Lobby) rejoin(m *member, token string) error {
+	l.mu.Lock()
+	var rooms []*room
+	for _, r := range l.rooms {
+		if r.server != nil {
+			rooms = append(rooms, r)
+		}
+	}
+	l.mu.Unlock()
+	for _, r := range rooms {
+		seat, err := r.server.Rejoin(token, m.client)
+		if err != nil {
+			continue
+		}
+		l.mu.Lock()
+		m.room, m.server, m.seat = r, r.server, seat
+		l.mu.Unlock()
+		return nil
+	}
+	return errors.New("no game has a seat with that token")
+}
+
+func (l *Lobby) dispatch(ctx context.Context, m *member, msg *Message) error {
+	if msg.Type == MsgChat {
+		l.mu.Lock()
+		srv, seat := m.server, m.seat
+		l.mu.Unlock()
+		if srv == nil {
+			return errors.New("there's no one to chat to until your game starts")
+		}
+		return srv.Say(seat, msg.Text)
+	}
+	if msg.Type == MsgMove {
+		l.mu.Lock()
+		srv, seat := m.server, m.seat
+		l.mu.Unlock()
+		if srv == nil {
+			return errors.New("your game hasn't started")
+		}
+		keys := []rune(msg.Key)
+		if len(keys) != 1 {
+			return fmt.Errorf("key %q is not a single digit", msg.Key)
+		}
+		return srv.Press(ctx, seat, keys[0])
+	}
+	if msg.Type == MsgDeal || msg.Type == MsgReveal {
+		l.mu.Lock()
+		srv, seat := m.server, m.seat
+		l.mu.Unlock()
+		if srv == nil {
+			return fmt.Errorf("unexpected %q message", msg.Type)
+		}
+		return srv.answer(seat, msg)
+	}
+	if msg.Type == MsgWatch {
+		if msg.Room == nil {
+			return errors.New("no room given")
+		}
+		return l.watch(m, msg.Room.Name)
+	}
+
+	r, err := l.request(m, msg)
+	if r != nil {
+		l.start(ctx, r)
+	}
+	return err
+}
+
+// request handles a message about the rooms themselves. It returns the room
+// if everyone in it is now ready, for the caller to start once l.mu is
+// released.
+func (l *Lobby) request(m *member, msg *Message) (*room, error) {
+	l.mu.Lock()
+	defer l.unlock()
+	if m.server != nil || m.watching != nil || m.room != nil && m.room.info.Started {
+		return nil, fmt.Errorf("can't %s during a game", msg.Type)
+	}
+	switch msg.Type {
+	case MsgList:
+		l.post(m, &Message{Type: MsgRooms, Rooms: l.roomInfos()})
+		return nil, nil
+	case MsgCreate:
+		if msg.Room == nil {
+			return nil, errors.New("no room given")
+		}
+		return nil, l.create(m, *msg.Room)
+	case MsgJoin:
+		if msg.Room == nil {
+			return nil, errors.New("no room given")
+		}
+		return nil, l.join(m, msg.Room.Name)
+	case MsgLeave:
+		if m.room == nil {
+			return nil, errors.New("not in a room")
+		}
+		l.leave(m)
+		return nil, nil
+	case MsgReady:
+		if m.room == nil {
+			return nil, errors.New("not in a room")
+		}
+		m.ready = msg.Ready
+		if l.allReady(m.room) {
+			// Nobody can come or go from here on, while start seats them.
+			m.room.info.Started = true
+			return m.room, nil
+		}
+		l.notify(m.room)
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unexpected %q message", msg.Type)
+	}
+}
+
+// watch has m spectate the game in the room called name. The game's server
+// welcomes them, which it does without l.mu held.
+func (l *Lobby) watch(m *member, name string) error {
+	l.mu.Lock()
+	r := l.find(name)
+	var srv *Server
+	var err error
+	switch {
+	case m.server != nil || m.watching != nil:
+		err = fmt.Errorf("can't %s during a game", MsgWatch)
+	case m.room != nil:
+		err = errors.New("already in a room")
+	case r == nil:
+		err = fmt.Errorf("no room called %q", name)
+	case r.server == nil:
+		err = fmt.Errorf("%s hasn't started yet", name)
+	default:
+		srv = r.server
+	}
+	l.unlock()
+	if err != nil {
+		return err
+	}
+	if err := srv.Watch(m.client); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	m.watching = srv
+	l.unlock()
+	return nil
+}
+
+// unlock releases l.mu, then sends everything posted while it was held. The
+// sends are made in the order they were posted.
+func (l *Lobby) unlock() {
+	out := l.outbox
+	l.outbox = nil
+	l.sending.Lock()
+	defer l.sending.Unlock()
+	l.mu.Unlock()
+	for _, d := range out {
+		d.to.send(d.msg)
+	}
+}
+
+// The helpers below expect l.mu to be held.
+
+// post queues msg for m until l.mu is released.
+func (l *Lobby) post(m *member, msg *Message) {
+	l.outbox = append(l.outbox, delivery{to: m, msg: msg})
+}
+
+func (l *Lobby) find(name string) *room {
+	for _, r := range l.rooms {
+		if r.info.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+func (l *Lobby) create(m *member, info RoomInfo) error {
+	if m.room != nil {
+		return errors.New("already in a room")
+	}
+	if info.Players < 3 || info.Players > 6 {
+		return fmt.Errorf("a game needs 3 to 6 players, not %d", info.Players)
+	}
+	info.Name = cleanName(info.Name)
+	if info.Name == "" {
+		info.Name = cleanName(fmt.Sprintf("%s's table", m.name))
+	}
+	if l.find(info.Name) != nil {
+		return fmt.Errorf("there is already a room called %q", info.Name)
+	}
+	r := room{info: RoomInfo{Name: info.Name, Players: info.Players, Advisor: info.Advisor, Reveal: info.Reveal}}
+	l.rooms = append(l.rooms, &r)
+	return l.join(m, r.info.Name)
+}
+
+func (l *Lobby) join(m *member, name string) error {
+	if m.room != nil {
+		return errors.New("already in a room")
+	}
+	r := l.find(name)
+	switch {
+	case r == nil:
+		return fmt.Errorf("no room called %q", name)
+	case r.info.Started:
+		return fmt.Errorf("%s has already started", name)
+	case len(r.members) >= r.info.Players:
+		return fmt.Errorf("%s is full", name)
+	case slices.ContainsFunc(r.members, func(other *member) bool { return other.name == m.name }):
+		return fmt.Errorf("there is already a %s in %s", m.name, name)
+	}
+	r.members = append(r.members, m)
+	m.room, m.ready = r, false
+	l.notify(r)
+	l.announce()
+	return nil
+}
+
+func (l *Lobby) leave(m *member) {
+	r := m.room
+	r.members = slices.DeleteFunc(r.members, func(other *member) bool { return other == m })
+	m.room, m.ready = nil, false
+	if len(r.members) == 0 {
+		l.rooms = slices.DeleteFunc(l.rooms, func(other *room) bool { return other == r })
+	} else {
+		l.notify(r)
+	}
+	l.announce()
+}
+
+func (l *Lobby) drop(m *member) {
+	l.mu.Lock()
+	defer l.unlock()
+	delete(l.members, m)
+	if m.room != nil && !m.room.info.Started {
+		l.leave(m)
+	}
+}
+
+func (l *Lobby) allReady(r *room) bool {
+	for _, m := range r.members {
+		if !m.ready {
+			return false
+		}
+	}
+	return true
+}
+
+// start hands the members of a room marked as started over to a Server of
+// their own, which fills the empty seats with bots. They are seated without
+// l.mu held, as seating them sends them their welcomes. If any of them can't
+// be seated, the room goes back to waiting for everyone to be ready.
+func (l *Lobby) start(ctx context.Context, r *room) {
+	l.mu.Lock()
+	members := slices.Clone(r.members)
+	info := l.roomInfo(r)
+	l.unlock()
+
+	seats := make([]int, len(members))
+	srv, err := New(info.Players, len(members))
+	if err == nil {
+		srv.Room = &info
+		srv.Reveal = info.Reveal
+		for i, m := range members {
+			if seats[i], err = srv.Join(m.name, m.client); err != nil {
+				break
+			}
+		}
+	}
+
+	l.mu.Lock()
+	defer l.unlock()
+	if err != nil {
+		l.abort(r, err)
+		return
+	}
+	r.server = srv
+	for i, m := range members {
+		m.server, m.seat = srv, seats[i]
+	}
+	l.announce()
+	go func() {
+		if err := srv.Run(ctx); err != nil {
+			game.Debugf("room %s: %v", r.info.Name, err)
+		}
+		l.mu.Lock()
+		defer l.unlock()
+		l.rooms = slices.DeleteFunc(l.rooms, func(other *room) bool { return other == r })
+		l.announce()
+	}()
+}
+
+// abort puts a room whose game couldn't start back to waiting, and tells its
+// members why. Anyone who left while they were being seated is let go.
+func (l *Lobby) abort(r *room, err error) {
+	game.Debugf("room %s: %v", r.info.Name, err)
+	r.info.Started = false
+	for _, m := range slices.Clone(r.members) {
+		m.ready = false
+		if !l.members[m] {
+			l.leave(m)
+			continue
+		}
+		l.post(m, &Message{Type: MsgError, Error: fmt.Sprintf("the game couldn't start: %v", err)})
+	}
+	if len(r.members) > 0 {
+		l.notify(r)
+	}
+	l.announce()
+}
+
+// notify sends the room as it stands to everyone in it.
+func (l *Lobby) notify(r *room) {
+	info := l.roomInfo(r)
+	for _, m := range r.members {
+		l.post(m, &Message{Type: MsgRoom, Room: &info})
+	}
+}
+
+// announce sends the list of rooms to everyone who isn't in one.
+func (l *Lobby) announce() {
+	rooms := l.roomInfos()
+	for m := range l.members {
+		if m.room == nil && m.watching == nil {
+			l.post(m, &Message{Type: MsgRooms, Rooms: rooms})
+		}
+	}
+}
+
+func (l *Lobby) roomInfos() []RoomInfo {
+	var infos []RoomInfo
+	for _, r := range l.rooms {
+		infos = append(infos, l.roomInfo(r))
+	}
+	return infos
+}
+
+// roomInfo fills in the room's members, and the bots that will take the seats
+// left empty, in the order the Server picks them.
+func (l *Lobby) roomInfo(r *room) RoomInfo {
+	info := r.info
+	info.Members = nil
+	var names []string
+	for _, m := range r.members {
+		info.Members = append(info.Members, Member{Name: m.name, Ready: m.ready})
+		names = append(names, m.name)
+	}
+	info.Bots = nil
+	for _, name := range game.BOT_NAMES {
+		if len(names)+len(info.Bots) >= info.Players {
+			break
+		}
+		if !slices.Contains(names, name) {
+			info.Bots = append(info.Bots, name)
+		}
+	}
+	return info
+}
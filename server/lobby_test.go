@@ -0,0 +1,161 @@
+package server
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"slices"
+	"testing"
+
+	"kugo/game"
+)
+
+func startTestLobby(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go NewLobby().Serve(ctx, ln)
+	return ln.Addr().String()
+}
+
+// receiveType skips messages until one of the wanted type arrives.
+func (tc *testClient) receiveType(t *testing.T, want MessageType) *Message {
+	t.Helper()
+	for {
+		msg := tc.receive(t)
+		if msg.Type == want {
+			return msg
+		}
+	}
+}
+
+func TestLobbyStartsRoomWhenReady(t *testing.T) {
+	addr := startTestLobby(t)
+	ann := dialTestClient(t, addr, "Ann")
+	assertMessage(t, ann.receive(t).Type, MsgRooms)
+	ben := dialTestClient(t, addr, "Ben")
+	assertMessage(t, ben.receive(t).Type, MsgRooms)
+
+	ann.send(t, &Message{Type: MsgCreate, Room: &RoomInfo{Name: "Friday", Players: 3}})
+	room := ann.receiveType(t, MsgRoom).Room
+	if len(room.Members) != 1 || len(room.Bots) != 2 {
+		t.Fatalf("new room has %d members and %d bots, want 1 and 2", len(room.Members), len(room.Bots))
+	}
+	rooms := ben.receiveType(t, MsgRooms).Rooms
+	if len(rooms) != 1 || rooms[0].Name != "Friday" {
+		t.Fatalf("Ben sees rooms %v, want Friday", rooms)
+	}
+
+	ben.send(t, &Message{Type: MsgJoin, Room: &RoomInfo{Name: "Friday"}})
+	ben.receiveType(t, MsgRoom)
+	ann.send(t, &Message{Type: MsgReady, Ready: true})
+	for {
+		room = ben.receiveType(t, MsgRoom).Room
+		if room.Members[0].Ready {
+			break
+		}
+	}
+	if room.Members[1].Ready {
+		t.Fatalf("Ben is ready before saying so")
+	}
+	ben.send(t, &Message{Type: MsgReady, Ready: true})
+
+	for seat, tc := range []*testClient{ann, ben} {
+		welcome := tc.receiveType(t, MsgWelcome)
+		if welcome.Seat != seat || welcome.Room.Name != "Friday" {
+			t.Errorf("welcomed to seat %d of %v, want seat %d of Friday", welcome.Seat, welcome.Room, seat)
+		}
+		view := tc.receiveType(t, MsgView).View
+		if len(view.Players) != 3 || view.Players[2].Name != room.Bots[0] {
+			t.Errorf("seat %d sees players %v, want Ann, Ben and %s", seat, view.Players, room.Bots[0])
+		}
+	}
+
+	ann.send(t, &Message{Type: MsgMove, Key: "1"})
+	selectAction := game.State{Phase: game.SelectAction, Action: game.NoAction}
+	ben.receiveView(t, selectAction, 1)
+}
+
+func TestLobbyRejectsFullRoom(t *testing.T) {
+	addr := startTestLobby(t)
+	var clients []*testClient
+	for _, name := range []string{"Ann", "Ben", "Cat", "Dan"} {
+		tc := dialTestClient(t, addr, name)
+		tc.receiveType(t, MsgRooms)
+		clients = append(clients, tc)
+	}
+	clients[0].send(t, &Message{Type: MsgCreate, Room: &RoomInfo{Name: "Small", Players: 3}})
+	clients[0].receiveType(t, MsgRoom)
+	for _, tc := range clients[1:3] {
+		tc.send(t, &Message{Type: MsgJoin, Room: &RoomInfo{Name: "Small"}})
+		tc.receiveType(t, MsgRoom)
+	}
+	clients[3].send(t, &Message{Type: MsgJoin, Room: &RoomInfo{Name: "Small"}})
+	clients[3].receiveType(t, MsgError)
+}
+
+func TestLobbyStartRollsBack(t *testing.T) {
+	l := NewLobby()
+	ann := member{name: "Ann", ready: true}
+	annConn, annEnd := net.Pipe()
+	defer annEnd.Close()
+	ann.client = newClient(annConn)
+	defer ann.Close()
+	// Ben's connection is gone by the time the game starts, so seating him
+	// fails after Ann has been welcomed.
+	ben := member{name: "Ben", ready: true}
+	benConn, _ := net.Pipe()
+	ben.client = newClient(benConn)
+	ben.Close()
+
+	r := room{info: RoomInfo{Name: "Friday", Players: 3, Started: true}, members: []*member{&ann, &ben}}
+	ann.room, ben.room = &r, &r
+	l.rooms = []*room{&r}
+	l.members[&ann] = true
+	go l.start(context.Background(), &r)
+
+	tc := testClient{conn: annEnd, dec: json.NewDecoder(annEnd)}
+	tc.receiveType(t, MsgError)
+	room := tc.receiveType(t, MsgRoom).Room
+	if room.Started || len(room.Members) != 1 || room.Members[0].Ready {
+		t.Errorf("after a failed start Ann sees %+v, want Friday waiting with only her, not ready", room)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if r.server != nil || ann.server != nil {
+		t.Errorf("the room kept the game that couldn't start")
+	}
+}
+
+func TestLobbySeedsFromMembers(t *testing.T) {
+	addr := startTestLobby(t)
+	entropy := game.NewSeed()
+	ann := dialTestClientWith(t, addr, &Message{Type: MsgHello, Name: "Ann", Commitment: entropy.Commitment()})
+	ann.receiveType(t, MsgRooms)
+	ann.send(t, &Message{Type: MsgCreate, Room: &RoomInfo{Name: "Friday", Players: 3}})
+	ann.receiveType(t, MsgRoom)
+	ann.send(t, &Message{Type: MsgReady, Ready: true})
+	ann.receiveType(t, MsgWelcome)
+
+	ask := ann.receiveType(t, MsgReveal)
+	want := game.Contribution{Player: 0, Commitment: entropy.Commitment()}
+	if len(ask.Contributions) != 2 || ask.Contributions[1] != want {
+		t.Fatalf("asked to reveal against %+v, want the host and %+v", ask.Contributions, want)
+	}
+	ann.send(t, &Message{Type: MsgReveal, Entropy: hex.EncodeToString(entropy[:])})
+	view := ann.receiveType(t, MsgView).View
+	if !slices.Contains(view.Log, "The players added entropy of their own to the seed") {
+		t.Errorf("the seed was made without Ann's entropy: %q", view.Log)
+	}
+}
+
+func TestLobbyRefusesBadCommitment(t *testing.T) {
+	addr := startTestLobby(t)
+	ann := dialTestClientWith(t, addr, &Message{Type: MsgHello, Name: "Ann", Commitment: "not a hash"})
+	assertMessage(t, ann.receive(t).Type, MsgError)
+}
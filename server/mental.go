@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"kugo/game"
+	"kugo/mental"
+)
+
+// keyholding is a Sink whose client holds its own keys for a mental poker
+// deal.
+type keyholding interface {
+	holdsKeys() bool
+}
+
+// Answer carries out step on kh, as a client does for the deal steps it is
+// sent, and returns the answer to send back.
+func (step *DealStep) Answer(kh mental.Keyholder) (*DealStep, error) {
+	answer := DealStep{Op: step.Op, Slot: step.Slot}
+	var err error
+	switch step.Op {
+	case DealShuffle:
+		answer.Deck, err = kh.Shuffle(step.Deck)
+	case DealRelock:
+		answer.Deck, err = kh.Relock(step.Slots, step.Deck)
+	case DealShare:
+		answer.Deck, err = kh.Share(step.Slots, step.Deck)
+	case DealKey:
+		answer.Key, err = kh.Key(step.Slot)
+	case DealCard:
+		// The card is the player's alone, so it stays out of the answer.
+		_, err = kh.Deal(step.Slot, step.Card)
+	default:
+		err = fmt.Errorf("no such deal step as %q", step.Op)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &answer, nil
+}
+
+// dealTable shuffles the deck between the players by mental poker. Seats
+// whose clients hold their own keys are asked for each step over the
+// connection, and the host holds the keys for the rest.
+func (s *Server) dealTable(ctx context.Context) (*mental.Table, error) {
+	var holders []mental.Keyholder
+	s.mu.Lock()
+	for i := range s.NumPlayers {
+		if i < len(s.seats) {
+			if kh, ok := s.seats[i].sink.(keyholding); ok && kh.holdsKeys() {
+				holders = append(holders, remoteKeys{ctx: ctx, s: s, seat: i})
+				continue
+			}
+		}
+		holders = append(holders, mental.NewPeer(i))
+	}
+	s.mu.Unlock()
+	return mental.Deal(holders)
+}
+
+// remoteKeys is the Keyholder for a player whose keys are kept by their
+// client.
+type remoteKeys struct {
+	ctx  context.Context
+	s    *Server
+	seat int
+}
+
+func (rk remoteKeys) Shuffle(deck []*big.Int) ([]*big.Int, error) {
+	answer, err := rk.ask(&DealStep{Op: DealShuffle, Deck: deck})
+	if err != nil {
+		return nil, err
+	}
+	return answer.Deck, nil
+}
+
+func (rk remoteKeys) Relock(slots []int, deck []*big.Int) ([]*big.Int, error) {
+	answer, err := rk.ask(&DealStep{Op: DealRelock, Slots: slots, Deck: deck})
+	if err != nil {
+		return nil, err
+	}
+	return answer.Deck, nil
+}
+
+func (rk remoteKeys) Share(slots []int, deck []*big.Int) ([]*big.Int, error) {
+	answer, err := rk.ask(&DealStep{Op: DealShare, Slots: slots, Deck: deck})
+	if err != nil {
+		return nil, err
+	}
+	return answer.Deck, nil
+}
+
+func (rk remoteKeys) Key(slot int) (*big.Int, error) {
+	answer, err := rk.ask(&DealStep{Op: DealKey, Slot: slot})
+	if err != nil {
+		return nil, err
+	}
+	return answer.Key, nil
+}
+
+// Deal hands the card to the player's client, which keeps it to itself.
+func (rk remoteKeys) Deal(slot int, card *big.Int) (game.Card, error) {
+	if _, err := rk.ask(&DealStep{Op: DealCard, Slot: slot, Card: card}); err != nil {
+		return game.NoCard, err
+	}
+	return game.NoCard, nil
+}
+
+// ask sends the player a step of the deal and waits for their answer.
+func (rk remoteKeys) ask(step *DealStep) (*DealStep, error) {
+	answer, err := rk.s.ask(rk.ctx, rk.seat, &Message{Type: MsgDeal, Seat: rk.seat, Deal: step})
+	if err != nil {
+		return nil, err
+	}
+	if answer.Deal == nil || answer.Deal.Op != step.Op {
+		return nil, fmt.Errorf("%w: %s answered the wrong step of the deal", mental.ErrCheat, rk.s.nameOf(rk.seat))
+	}
+	return answer.Deal, nil
+}
@@ -0,0 +1,224 @@
+// Package server hosts a kugo game for players connecting over the network.
+//
+// # Protocol
+//
+// Clients and the server exchange Message values encoded as JSON, one per
+// line. A session goes like this:
+//
+//  1. The client connects and sends {"type":"hello","name":"Bob"}.
+//  2. The server replies {"type":"welcome","seat":N,"token":"..."}, where N
+//     is the index of the player the client controls. A name that is empty or
+//     already taken gets an "error" message instead and the connection is
+//     closed.
+//  3. Once every human seat is filled the game starts, and the server sends
+//     {"type":"view","view":{...}} to each client whenever the game changes.
+//     The view is a game.View redacted for the client's seat, so it never
+//     holds another player's hand.
+//  4. The client sends {"type":"move","key":"3"} to press a key, exactly as a
+//     local player would. Keys must be a single digit. Moves that are out of
+//     range or sent by a player who isn't being asked are ignored, just as
+//     they are for local input.
+//  5. When the game ends the last view has the EndGame phase and the server
+//     closes the connection.
+//
+// Every view carries the hash of the seed the deck was shuffled from, which
+// is fixed before the cards are dealt. The last view also carries the seed
+// and every card drawn and returned, so clients can check the deal with
+// game.VerifyShuffle.
+//
+// So that the host can't pick the seed, players can add entropy of their own
+// to it. A client that puts the hash of 32 random bytes in its hello, as
+// {"type":"hello","name":"Bob","commitment":"..."}, is sent
+// {"type":"reveal","contributions":[...]} once every seat is filled, listing
+// the commitment of the host and every player, and answers with
+// {"type":"reveal","entropy":"..."}, the bytes in hex. The seed is the hash of
+// everyone's entropy in the order listed, and the last view's record holds it
+// all, so each player can check that theirs went in and that nobody else's
+// changed. A player who doesn't answer, or whose entropy doesn't match their
+// commitment, stops the game before it is dealt.
+//
+// A host can have the players deal between themselves by mental poker
+// instead, as the mental package describes. The views then carry no
+// commitment, as there is no seed for the host to reveal. A client that says
+// {"type":"hello","name":"Bob","keys":true} holds its own keys, and is sent
+// {"type":"deal","deal":{"op":"shuffle","deck":[...]}} for each step of the
+// deal it takes part in. It answers with the same message type and op, and
+// the "deck" or "key" the step calls for, or an "error" if it won't. The ops
+// are those of mental.Keyholder: "shuffle" and "relock" the deck while it is
+// shuffled, "share" it before it is shuffled again, "key" for the card in a
+// slot, and "card" to be dealt the one in "slot", which comes as "card" with
+// every other lock taken off. That last is answered with nothing but the op,
+// so the host never learns the card: it is sent in views as game.FaceDown of
+// its slot until it is shown, and the client fills it in for itself. The host
+// keeps the keys of every other seat itself. A step that
+// goes unanswered waits for the player to reconnect, and is sent again when
+// they do, but the keys are lost with the client that held them.
+//
+// Anything the server can't accept is answered with {"type":"error",
+// "error":"..."}.
+//
+// # Chat
+//
+// Players talk to the table with {"type":"chat","text":"..."}. The server
+// passes it on to every player and spectator as {"type":"chat","name":"Bob",
+// "text":"..."}, with anything that isn't printable taken out so it can't
+// tamper with anyone's terminal. Spectators can read the chat but not add to
+// it.
+//
+// # Reconnecting
+//
+// A client whose connection drops can come back by sending
+// {"type":"hello","token":"..."} with the token from its welcome. It is
+// welcomed to its old seat and sent the current view. If it takes longer than
+// the server's grace period, a bot plays the seat in the meantime and hands it
+// back on its return.
+//
+// # Spectating
+//
+// Anyone can watch instead of play by sending {"type":"hello","watch":true}.
+// They are welcomed with seat -1, game.Spectator, and sent views like the
+// players, but redacted so that no hand is shown. If the host allows it,
+// spectators see every hand instead, but the views are held back long enough
+// that they can't help anyone still playing. Moves from spectators are
+// refused.
+//
+// In a lobby, spectators send {"type":"watch","room":{"name":"Friday"}} to
+// watch a room's game once it has started.
+//
+// # Lobby
+//
+// A Lobby hosts any number of rooms instead of a single game, and adds a few
+// steps between the hello and the welcome:
+//
+//  1. The server answers the hello with {"type":"rooms","rooms":[...]}, and
+//     sends the list again whenever it changes until the client is in a room.
+//     {"type":"list"} asks for it at any time.
+//  2. {"type":"create","room":{"name":"Friday","players":4,"advisor":true}}
+//     opens a room with the given number of seats and rules, and seats the
+//     client in it. {"type":"join","room":{"name":"Friday"}} takes a seat in
+//     an open room, and {"type":"leave"} gives it up again.
+//  3. Members of a room are sent {"type":"room","room":{...}} whenever anyone
+//     joins, leaves or changes their mind, and say whether they are ready
+//     with {"type":"ready","ready":true}.
+//  4. Once everyone in the room is ready, the empty seats are filled with
+//     bots and each member is sent a welcome, which carries the room so the
+//     client knows the rules. From then on the session is the same as above.
+//
+// # Discovery
+//
+// Hosts can announce their game to the local network by broadcasting an
+// Announcement as JSON to UDP port 7778 every second, such as
+// {"port":7777,"lobby":true,"rooms":[...]}. A single game leaves out "lobby"
+// and describes itself as one room. Whoever is listening takes the host's
+// address from where the packet came from.
+//
+// # Web
+//
+// Browsers play over HTTP instead, through the endpoints listed on
+// WebHandler. They are sent the same messages as server-sent events, except
+// that each view is replaced by the display Model of the screen it makes.
+//
+// Programs can also play through the JSON API, which hands each seat the same
+// messages to poll for. See API for its endpoints.
+package server
+
+import (
+	"math/big"
+
+	"kugo/game"
+)
+
+// MessageType identifies the kind of a Message.
+type MessageType string
+
+const (
+	MsgHello   MessageType = "hello"
+	MsgWelcome MessageType = "welcome"
+	MsgMove    MessageType = "move"
+	MsgView    MessageType = "view"
+	MsgError   MessageType = "error"
+
+	MsgRooms  MessageType = "rooms"
+	MsgList   MessageType = "list"
+	MsgCreate MessageType = "create"
+	MsgJoin   MessageType = "join"
+	MsgLeave  MessageType = "leave"
+	MsgRoom   MessageType = "room"
+	MsgReady  MessageType = "ready"
+	MsgWatch  MessageType = "watch"
+	MsgChat   MessageType = "chat"
+	MsgDeal   MessageType = "deal"
+	MsgReveal MessageType = "reveal"
+)
+
+// Member is a player sitting in a lobby room.
+type Member struct {
+	Name  string `json:"name"`
+	Ready bool   `json:"ready"`
+}
+
+// RoomInfo describes a lobby room and the rules its game is played by.
+type RoomInfo struct {
+	Name    string   `json:"name"`
+	Players int      `json:"players,omitempty"`
+	Advisor bool     `json:"advisor,omitempty"`
+	Members []Member `json:"members,omitempty"`
+	Bots    []string `json:"bots,omitempty"`
+	Started bool     `json:"started,omitempty"`
+	Reveal  bool     `json:"reveal,omitempty"`
+}
+
+// clean makes the names in info safe to print, as cleanName does.
+func (info *RoomInfo) clean() {
+	info.Name = cleanName(info.Name)
+	for i := range info.Members {
+		info.Members[i].Name = cleanName(info.Members[i].Name)
+	}
+	for i := range info.Bots {
+		info.Bots[i] = cleanName(info.Bots[i])
+	}
+}
+
+// Message is the single envelope used in both directions. Only the fields
+// relevant to the Type are set.
+type Message struct {
+	Type  MessageType `json:"type"`
+	Name  string      `json:"name,omitempty"`
+	Seat  int         `json:"seat"`
+	Key   string      `json:"key,omitempty"`
+	View  *game.View  `json:"view,omitempty"`
+	Error string      `json:"error,omitempty"`
+	Room  *RoomInfo   `json:"room,omitempty"`
+	Rooms []RoomInfo  `json:"rooms,omitempty"`
+	Ready bool        `json:"ready,omitempty"`
+	Token string      `json:"token,omitempty"`
+	Watch bool        `json:"watch,omitempty"`
+	Text  string      `json:"text,omitempty"`
+	Keys  bool        `json:"keys,omitempty"`
+	Deal  *DealStep   `json:"deal,omitempty"`
+
+	Commitment    string              `json:"commitment,omitempty"`
+	Contributions []game.Contribution `json:"contributions,omitempty"`
+	Entropy       string              `json:"entropy,omitempty"`
+}
+
+// DealOp names a step of a mental poker deal.
+type DealOp string
+
+const (
+	DealShuffle DealOp = "shuffle"
+	DealRelock  DealOp = "relock"
+	DealShare   DealOp = "share"
+	DealKey     DealOp = "key"
+	DealCard    DealOp = "card"
+)
+
+// DealStep is one call to a player's mental.Keyholder, and its answer.
+type DealStep struct {
+	Op    DealOp     `json:"op"`
+	Slots []int      `json:"slots,omitempty"`
+	Deck  []*big.Int `json:"deck,omitempty"`
+	Slot  int        `json:"slot,omitempty"`
+	Key   *big.Int   `json:"key,omitempty"`
+	Card  *big.Int   `json:"card,omitempty"`
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"context"
+	"encoding/hex"
+
+	"kugo/game"
+)
+
+// committing is a Sink whose client has committed to entropy of its own for
+// the seed.
+type committing interface {
+	commitment() string
+}
+
+// reseed has the deck shuffled from entropy of the host's own together with
+// that of every player who committed to some in their hello. Everyone's
+// commitment goes out with each request to reveal, so nobody reveals until
+// they are all fixed. With nobody committed, the Controller's own seed is
+// left as it is.
+func (s *Server) reseed(ctx context.Context) error {
+	host := game.NewSeed()
+	contributions := []game.Contribution{{
+		Player:     -1,
+		Commitment: host.Commitment(),
+		Entropy:    hex.EncodeToString(host[:]),
+	}}
+	s.mu.Lock()
+	for i, st := range s.seats {
+		if st.commitment != "" {
+			contributions = append(contributions, game.Contribution{Player: i, Commitment: st.commitment})
+		}
+	}
+	s.mu.Unlock()
+	if len(contributions) == 1 {
+		return nil
+	}
+	shown := make([]game.Contribution, len(contributions))
+	for i, con := range contributions {
+		shown[i] = game.Contribution{Player: con.Player, Commitment: con.Commitment}
+	}
+	for i := 1; i < len(contributions); i++ {
+		con := &contributions[i]
+		answer, err := s.ask(ctx, con.Player, &Message{Type: MsgReveal, Seat: con.Player, Contributions: shown})
+		if err != nil {
+			return err
+		}
+		con.Entropy = answer.Entropy
+	}
+	return s.controller.Reseed(contributions)
+}
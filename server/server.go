@@ -0,0 +1,652 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"kugo/game"
+	inp "kugo/input"
+)
+
+var ErrFull = errors.New("game is full")
+
+// Sink is where a seated player's updates go. Each kind of connection the
+// server accepts, such as a TCP client or an SSH session, provides its own.
+// Send should not block for long: a connection queues what it is sent, and
+// drops out if it falls too far behind.
+type Sink interface {
+	Send(msg *Message) error
+	Close() error
+}
+
+// DefaultRevealDelay is how far behind the game a spectator who can see every
+// hand is kept, so they can't pass on what they see to the players in time
+// to matter.
+const DefaultRevealDelay = 2 * time.Minute
+
+// DefaultGrace is how long a dropped player has to reconnect before a bot
+// takes over their seat.
+const DefaultGrace = 30 * time.Second
+
+type seat struct {
+	name      string
+	token     string
+	sink      Sink
+	connected bool
+	grace     *time.Timer
+	stopBot   context.CancelFunc
+	// commitment is the hash of the entropy the player's client will
+	// reveal towards the seed, if it said it would in its hello.
+	commitment string
+	// pending is what the player's client has been asked and not yet
+	// answered, such as a step of a mental poker deal, and answers where
+	// the answer goes.
+	pending *Message
+	answers chan *Message
+}
+
+// Server owns the Controller for a hosted game. Remote humans take the first
+// NumHumans seats in the order they join, and bots fill the rest. A human who
+// drops out can reconnect with the token they were welcomed with, and a bot
+// keeps their seat warm if they are gone for longer than Grace.
+type Server struct {
+	NumPlayers int
+	NumHumans  int
+	Grace      time.Duration
+	// Room, if set, is sent along with each welcome.
+	Room *RoomInfo
+	// Reveal lets spectators see every hand, RevealDelay after the fact.
+	// Otherwise they only see what the players can all see, as it happens.
+	Reveal      bool
+	RevealDelay time.Duration
+	// Mental has the players deal the deck between themselves by mental
+	// poker, so that not even the host can stack it. Clients that hold
+	// their own keys are asked for them over the connection.
+	Mental     bool
+	spectators []*spectator
+	lastView   *game.View
+	over       bool
+	controller *game.Controller
+	handler    *inp.InputHandler
+	seats      []*seat
+	welcomed   int
+	mu         sync.Mutex
+	full       chan struct{}
+	started    chan struct{}
+	done       chan struct{}
+	notes      chan string
+	chanErr    chan error
+}
+
+func New(numPlayers, numHumans int) (*Server, error) {
+	if numPlayers < 3 || numPlayers > 6 {
+		return nil, fmt.Errorf("a game needs 3 to 6 players, not %d", numPlayers)
+	}
+	if numHumans < 1 || numHumans > numPlayers {
+		return nil, fmt.Errorf("need between 1 and %d human players, not %d", numPlayers, numHumans)
+	}
+	s := Server{
+		NumPlayers:  numPlayers,
+		NumHumans:   numHumans,
+		Grace:       DefaultGrace,
+		RevealDelay: DefaultRevealDelay,
+		full:        make(chan struct{}),
+		started:     make(chan struct{}),
+		done:        make(chan struct{}),
+		notes:       make(chan string),
+		chanErr:     make(chan error),
+	}
+	return &s, nil
+}
+
+// Join seats a player who will be kept up to date through sink, and sends
+// them a welcome with their seat index and reconnection token. Their name is
+// cut down to what is safe to show the others. The game
+// starts as soon as the last human seat is taken and welcomed.
+func (s *Server) Join(name string, sink Sink) (int, error) {
+	name = cleanName(name)
+	s.mu.Lock()
+	if len(s.seats) >= s.NumHumans {
+		s.mu.Unlock()
+		return 0, ErrFull
+	}
+	if name == "" || slices.ContainsFunc(s.seats, func(other *seat) bool {
+		return other.name == name
+	}) {
+		s.mu.Unlock()
+		return 0, fmt.Errorf("name %q is empty or already taken", name)
+	}
+	// The seat is held for the player while they are welcomed, but nothing
+	// is sent to it until they have been.
+	st := seat{name: name, token: rand.Text(), sink: sink, answers: make(chan *Message, 1)}
+	if c, ok := sink.(committing); ok {
+		st.commitment = c.commitment()
+	}
+	idx := len(s.seats)
+	s.seats = append(s.seats, &st)
+	s.mu.Unlock()
+
+	err := sink.Send(&Message{Type: MsgWelcome, Seat: idx, Token: st.token, Room: s.Room})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st.connected = true
+	if err != nil {
+		// They never heard which seat is theirs, so it goes the way of any
+		// other a player has dropped out of.
+		s.leave(idx, err)
+	}
+	s.welcomed++
+	if s.welcomed == s.NumHumans {
+		close(s.full)
+	}
+	return idx, err
+}
+
+// Rejoin puts a player back in the seat they were given token for, handing it
+// back from the bot if one has taken over. Like Join, it welcomes them, and
+// they are sent the current view straight after, along with anything they
+// were asked and still owe an answer to.
+func (s *Server) Rejoin(token string, sink Sink) (int, error) {
+	s.mu.Lock()
+	idx, err := s.tokenSeat(token)
+	s.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	select {
+	case <-s.done:
+		return 0, errors.New("game is over")
+	default:
+	}
+	if err := sink.Send(&Message{Type: MsgWelcome, Seat: idx, Token: token, Room: s.Room}); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	old := s.reseat(idx, sink)
+	pending := s.seats[idx].pending
+	s.mu.Unlock()
+	if old != sink {
+		old.Close()
+	}
+	if pending != nil {
+		sink.Send(pending)
+	}
+	return idx, nil
+}
+
+// tokenSeat is the seat token was given for. The caller must hold s.mu.
+func (s *Server) tokenSeat(token string) (int, error) {
+	idx := slices.IndexFunc(s.seats, func(st *seat) bool { return st.token == token })
+	if token == "" || idx < 0 {
+		return 0, errors.New("no seat has that token")
+	}
+	return idx, nil
+}
+
+// seated reports whether a player called name has a seat.
+func (s *Server) seated(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.ContainsFunc(s.seats, func(st *seat) bool { return st.name == name })
+}
+
+// reseat hands a seat to a player's new sink, and returns the one it
+// replaces. It expects s.mu to be held.
+func (s *Server) reseat(idx int, sink Sink) Sink {
+	st := s.seats[idx]
+	old := st.sink
+	st.sink, st.connected = sink, true
+	if st.grace != nil {
+		st.grace.Stop()
+		st.grace = nil
+	}
+	if st.stopBot != nil {
+		st.stopBot()
+		st.stopBot = nil
+	}
+	s.note(fmt.Sprintf("%s is back", st.name))
+	return old
+}
+
+// Watch adds a spectator, who is welcomed with the Spectator seat and sent
+// views of the game for as long as it lasts. Any number can watch, and they
+// can turn up at any point.
+func (s *Server) Watch(sink Sink) error {
+	if err := sink.Send(&Message{Type: MsgWelcome, Seat: game.Spectator, Room: s.Room}); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.over {
+		return errors.New("game is over")
+	}
+	var delay time.Duration
+	if s.Reveal {
+		delay = s.RevealDelay
+	}
+	sp := newSpectator(sink, delay)
+	s.spectators = append(s.spectators, sp)
+	if s.lastView != nil {
+		sp.push(s.lastView)
+	}
+	return nil
+}
+
+// Unwatch removes a spectator who has gone.
+func (s *Server) Unwatch(sink Sink) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.spectators = slices.DeleteFunc(s.spectators, func(sp *spectator) bool {
+		if sp.sink == sink {
+			sp.stop()
+			return true
+		}
+		return false
+	})
+}
+
+// Say passes a line of chat from the player in the given seat to everyone in
+// the game, players and spectators alike.
+func (s *Server) Say(seatIdx int, text string) error {
+	text = cleanChat(text)
+	if text == "" {
+		return errors.New("nothing to say")
+	}
+	s.mu.Lock()
+	msg := Message{Type: MsgChat, Name: s.seats[seatIdx].name, Text: text}
+	sinks := s.connected()
+	for _, sp := range s.spectators {
+		sinks = append(sinks, sp.sink)
+	}
+	s.mu.Unlock()
+	for _, sink := range sinks {
+		sink.Send(&msg)
+	}
+	return nil
+}
+
+// connected lists the sinks of the players who are connected, by seat, with
+// nil for those who aren't. It expects s.mu to be held.
+func (s *Server) connected() []Sink {
+	sinks := make([]Sink, len(s.seats))
+	for i, st := range s.seats {
+		if st.connected {
+			sinks[i] = st.sink
+		}
+	}
+	return sinks
+}
+
+// MaxNameLength is the most runes a player or room name can hold.
+const MaxNameLength = 24
+
+// cleanChat trims text down to something that is safe to print on another
+// player's screen.
+func cleanChat(text string) string {
+	return clean(text, inp.MaxChatLength)
+}
+
+// cleanName is cleanChat for the names of players and rooms.
+func cleanName(name string) string {
+	return clean(name, MaxNameLength)
+}
+
+// clean drops anything from text that can't be printed, such as the escape
+// sequences a terminal would act on, and cuts it to at most limit runes.
+func clean(text string, limit int) string {
+	text = strings.Map(func(r rune) rune {
+		if !unicode.IsPrint(r) {
+			return -1
+		}
+		return r
+	}, text)
+	text = strings.TrimSpace(text)
+	if runes := []rune(text); len(runes) > limit {
+		text = strings.TrimSpace(string(runes[:limit]))
+	}
+	return text
+}
+
+// Press passes a key from the player in the given seat to the game, where the
+// InputHandler validates it exactly as it does local input. It waits for the
+// game to start if need be.
+func (s *Server) Press(ctx context.Context, seatIdx int, key rune) error {
+	if key < '0' || key > '9' {
+		return fmt.Errorf("key %q is not a single digit", key)
+	}
+	select {
+	case <-s.started:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case s.handler.PlayerChans[seatIdx] <- key:
+		return nil
+	case <-s.done:
+		return errors.New("game is over")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Leave reports that the player in the given seat has lost sink, their
+// connection. The seat is held for them for Grace, then a bot takes over until
+// they Rejoin. A sink that has already been replaced by a Rejoin is ignored.
+func (s *Server) Leave(seatIdx int, sink Sink, reason error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.seats[seatIdx]
+	if st.sink != sink || !st.connected {
+		return
+	}
+	s.leave(seatIdx, reason)
+}
+
+// leave starts the grace period for a player who has dropped out. It expects
+// s.mu to be held.
+func (s *Server) leave(seatIdx int, reason error) {
+	st := s.seats[seatIdx]
+	st.connected = false
+	game.Debugf("%s dropped: %v", st.name, reason)
+	s.note(fmt.Sprintf("%s lost their connection", st.name))
+	st.grace = time.AfterFunc(s.Grace, func() {
+		s.takeOver(seatIdx)
+	})
+}
+
+// askTimeout is how long a player has to answer something the server asks
+// of their client, such as a step of the deal, which covers reconnecting if
+// they have to, before the game is called off.
+const askTimeout = 2 * time.Minute
+
+// ask sends msg to the player in the given seat and waits for them to answer
+// with a message of the same type. The question is kept on their seat until
+// then, so that it is sent again if they reconnect.
+func (s *Server) ask(ctx context.Context, seatIdx int, msg *Message) (*Message, error) {
+	s.mu.Lock()
+	st := s.seats[seatIdx]
+	select {
+	case <-st.answers:
+		// Left over from a question that had already been given up on.
+	default:
+	}
+	st.pending = msg
+	sink := st.sink
+	if !st.connected {
+		sink = nil
+	}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		st.pending = nil
+		s.mu.Unlock()
+	}()
+	if sink != nil {
+		// If this fails the player has dropped, and is asked again when
+		// they are back.
+		sink.Send(msg)
+	}
+
+	timeout := time.NewTimer(askTimeout)
+	defer timeout.Stop()
+	select {
+	case answer := <-st.answers:
+		if answer.Error != "" {
+			return nil, fmt.Errorf("%s refused: %s", st.name, answer.Error)
+		}
+		if answer.Type != msg.Type {
+			return nil, fmt.Errorf("%s answered a %q with a %q", st.name, msg.Type, answer.Type)
+		}
+		return answer, nil
+	case <-timeout.C:
+		return nil, fmt.Errorf("%s took too long to answer", st.name)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// answer passes on a player's answer to what they were asked.
+func (s *Server) answer(seatIdx int, msg *Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.seats[seatIdx]
+	if st.pending == nil {
+		return errors.New("nothing is waiting on an answer from you")
+	}
+	st.pending = nil
+	st.answers <- msg
+	return nil
+}
+
+// nameOf is the name of the player in the given seat.
+func (s *Server) nameOf(seatIdx int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seats[seatIdx].name
+}
+
+// takeOver starts a bot in a seat whose player hasn't come back, or ends the
+// game if nobody is left to play it.
+func (s *Server) takeOver(seatIdx int) {
+	select {
+	case <-s.started:
+	case <-s.done:
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.seats[seatIdx]
+	if st.connected || st.stopBot != nil {
+		return
+	}
+	if !slices.ContainsFunc(s.seats, func(other *seat) bool { return other.connected }) {
+		go func() {
+			select {
+			case s.chanErr <- errors.New("every player has left"):
+			case <-s.done:
+			}
+		}()
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	st.stopBot = cancel
+	s.note(fmt.Sprintf("A bot is playing for %s until they return", st.name))
+
+	// The bot gets a channel of its own, so that nothing it decided on
+	// before being stopped reaches the game once the player is back. It is
+	// buffered so the bot never blocks on a move that will be thrown away.
+	moves := make(chan rune, 1)
+	go s.handler.CreateBotInputStream(ctx, moves, seatIdx)
+	go func() {
+		for {
+			select {
+			case key := <-moves:
+				select {
+				case s.handler.PlayerChans[seatIdx] <- key:
+				case <-ctx.Done():
+					return
+				case <-s.done:
+					return
+				}
+			case <-ctx.Done():
+				return
+			case <-s.done:
+				return
+			}
+		}
+	}()
+}
+
+// note passes a line for the action log to the game loop.
+func (s *Server) note(msg string) {
+	go func() {
+		select {
+		case s.notes <- msg:
+		case <-s.done:
+		}
+	}()
+}
+
+// Run waits for every human seat to be filled, then plays the game to the end.
+func (s *Server) Run(ctx context.Context) error {
+	defer close(s.done)
+	select {
+	case <-s.full:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer s.closeSinks()
+	defer s.stopBots()
+
+	players, err := s.createPlayers()
+	if err != nil {
+		return err
+	}
+	s.controller = game.NewController(players)
+	if s.Mental {
+		table, err := s.dealTable(ctx)
+		if err != nil {
+			return fmt.Errorf("shuffling by mental poker: %w", err)
+		}
+		s.controller.SetDealer(table)
+	} else if err := s.reseed(ctx); err != nil {
+		return fmt.Errorf("seeding the deck: %w", err)
+	}
+	s.controller.ShuffleAndDeal()
+	if err := s.controller.DealerErr(); err != nil {
+		return fmt.Errorf("dealing by mental poker: %w", err)
+	}
+	if s.Mental {
+		s.controller.Note("The deck is dealt by mental poker, so nobody can stack it")
+	} else {
+		// Every view carries the full commitment, but a glimpse of it in the
+		// log is something players can compare between them.
+		s.controller.Note(fmt.Sprintf("Deck committed to %s...", s.controller.Commitment()[:12]))
+		if len(s.controller.ShuffleRecord().Contributions) > 1 {
+			s.controller.Note("The players added entropy of their own to the seed")
+		}
+	}
+	s.handler = inp.NewInputHandler(players, s.chanErr)
+	close(s.started)
+	for i := len(s.seats); i < len(players); i++ {
+		go s.handler.CreateBotInputStream(ctx, s.handler.PlayerChans[i], i)
+	}
+	return s.run(ctx)
+}
+
+func (s *Server) closeSinks() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, st := range s.seats {
+		st.sink.Close()
+	}
+	for _, sp := range s.spectators {
+		sp.stop()
+	}
+	s.spectators = nil
+	s.over = true
+}
+
+func (s *Server) stopBots() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, st := range s.seats {
+		if st.grace != nil {
+			st.grace.Stop()
+		}
+		if st.stopBot != nil {
+			st.stopBot()
+		}
+	}
+}
+
+func (s *Server) createPlayers() ([]*game.Player, error) {
+	var players []*game.Player
+	var names []string
+	for _, st := range s.seats {
+		names = append(names, st.name)
+	}
+	for _, name := range game.BOT_NAMES {
+		if len(names) >= s.NumPlayers {
+			break
+		}
+		if slices.Contains(names, name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	if len(names) < s.NumPlayers {
+		return nil, fmt.Errorf("not enough bot names to fill %d seats", s.NumPlayers)
+	}
+	for i, name := range names {
+		p, err := game.NewPlayer(name, i, i < len(s.seats), false)
+		if err != nil {
+			return nil, err
+		}
+		players = append(players, p)
+	}
+	return players, nil
+}
+
+// broadcast sends everyone their view of the game. The sends happen once
+// s.mu is released, so a slow connection holds up no one else.
+func (s *Server) broadcast() {
+	s.mu.Lock()
+	sinks := s.connected()
+	if s.Reveal {
+		s.lastView = s.controller.RevealedView()
+	} else {
+		s.lastView = s.controller.View(game.Spectator)
+	}
+	for _, sp := range s.spectators {
+		sp.push(s.lastView)
+	}
+	s.mu.Unlock()
+	for i, sink := range sinks {
+		if sink != nil {
+			sink.Send(&Message{Type: MsgView, Seat: i, View: s.controller.View(i)})
+		}
+	}
+}
+
+// run is the server's equivalent of the local game loop, minus the display.
+func (s *Server) run(ctx context.Context) error {
+	s.broadcast()
+	for {
+		s.handler.UpdateStateData(s.controller.GetStateData())
+		inputChan := make(chan *game.InputData, 1)
+		go func() {
+			inputChan <- s.handler.GetInputData()
+		}()
+
+		var gotInput bool
+		for !gotInput {
+			select {
+			case inputData := <-inputChan:
+				s.controller.UpdateGame(inputData)
+				s.broadcast()
+				if err := s.controller.DealerErr(); err != nil {
+					return fmt.Errorf("the deal broke down: %w", err)
+				}
+				if s.controller.Phase == game.EndGame {
+					return nil
+				}
+				gotInput = true
+			case msg := <-s.notes:
+				s.controller.Note(msg)
+				s.broadcast()
+			case err := <-s.chanErr:
+				return err
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
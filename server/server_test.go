@@ -0,0 +1,360 @@
+package server
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"kugo/game"
+)
+
+type testClient struct {
+	conn net.Conn
+	dec  *json.Decoder
+	enc  *json.Encoder
+}
+
+func dialTestClient(t *testing.T, addr, name string) *testClient {
+	t.Helper()
+	return dialTestClientWith(t, addr, &Message{Type: MsgHello, Name: name})
+}
+
+func dialTestClientWith(t *testing.T, addr string, hello *Message) *testClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	tc := testClient{conn: conn, dec: json.NewDecoder(conn), enc: json.NewEncoder(conn)}
+	tc.send(t, hello)
+	return &tc
+}
+
+func (tc *testClient) send(t *testing.T, msg *Message) {
+	t.Helper()
+	if err := tc.enc.Encode(msg); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+}
+
+func (tc *testClient) receive(t *testing.T) *Message {
+	t.Helper()
+	var msg Message
+	if err := tc.dec.Decode(&msg); err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	return &msg
+}
+
+// receiveView skips views until one matches the wanted state and current
+// player.
+func (tc *testClient) receiveView(t *testing.T, want game.State, current int) *game.View {
+	t.Helper()
+	for {
+		msg := tc.receive(t)
+		if msg.Type == MsgView && msg.View.State == want && msg.View.Current == current {
+			return msg.View
+		}
+	}
+}
+
+func startTestServer(t *testing.T, players, humans int) string {
+	t.Helper()
+	s, err := New(players, humans)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return serveTest(t, s)
+}
+
+func serveTest(t *testing.T, s *Server) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go s.Serve(ctx, ln)
+	return ln.Addr().String()
+}
+
+func TestServerPlaysIncome(t *testing.T) {
+	addr := startTestServer(t, 3, 3)
+	var clients []*testClient
+	for i, name := range []string{"Ann", "Ben", "Cat"} {
+		tc := dialTestClient(t, addr, name)
+		welcome := tc.receive(t)
+		assertMessage(t, welcome.Type, MsgWelcome)
+		if welcome.Seat != i {
+			t.Fatalf("%s seated at %d, want %d", name, welcome.Seat, i)
+		}
+		clients = append(clients, tc)
+	}
+	selectAction := game.State{Phase: game.SelectAction, Action: game.NoAction}
+	for _, tc := range clients {
+		tc.receiveView(t, selectAction, 0)
+	}
+
+	clients[0].send(t, &Message{Type: MsgMove, Key: "1"})
+	for seat, tc := range clients {
+		view := tc.receiveView(t, selectAction, 1)
+		if view.Players[0].Coins != 3 {
+			t.Errorf("seat %d sees %d coins for Ann, want 3", seat, view.Players[0].Coins)
+		}
+		for _, p := range view.Players {
+			if p.Index != seat && len(p.CardsHeld) != 0 {
+				t.Errorf("seat %d can see %s's hand", seat, p.Name)
+			}
+			if p.Index == seat && len(p.CardsHeld) != 2 {
+				t.Errorf("seat %d can't see its own hand", seat)
+			}
+		}
+	}
+}
+
+func TestServerRejectsBadMoves(t *testing.T) {
+	addr := startTestServer(t, 3, 1)
+	tc := dialTestClient(t, addr, "Ann")
+	assertMessage(t, tc.receive(t).Type, MsgWelcome)
+
+	late := dialTestClient(t, addr, "Ben")
+	assertMessage(t, late.receive(t).Type, MsgError)
+
+	tc.send(t, &Message{Type: MsgMove, Key: "x"})
+	for {
+		msg := tc.receive(t)
+		if msg.Type == MsgView {
+			continue
+		}
+		assertMessage(t, msg.Type, MsgError)
+		return
+	}
+}
+
+// receiveNote skips views until one has note in its action log.
+func (tc *testClient) receiveNote(t *testing.T, note string) *game.View {
+	t.Helper()
+	for {
+		msg := tc.receive(t)
+		if msg.Type == MsgView && slices.Contains(msg.View.Log, note) {
+			return msg.View
+		}
+	}
+}
+
+func TestServerReconnect(t *testing.T) {
+	s, _ := New(3, 2)
+	addr := serveTest(t, s)
+	ann := dialTestClient(t, addr, "Ann")
+	assertMessage(t, ann.receive(t).Type, MsgWelcome)
+	ben := dialTestClient(t, addr, "Ben")
+	welcome := ben.receive(t)
+	assertMessage(t, welcome.Type, MsgWelcome)
+	if welcome.Token == "" {
+		t.Fatalf("welcomed without a token")
+	}
+	ann.receive(t)
+
+	ben.conn.Close()
+	ann.receiveNote(t, "Ben lost their connection")
+
+	bad := dialTestClientWith(t, addr, &Message{Type: MsgHello, Token: "not-a-token"})
+	assertMessage(t, bad.receive(t).Type, MsgError)
+
+	back := dialTestClientWith(t, addr, &Message{Type: MsgHello, Token: welcome.Token})
+	rewelcome := back.receive(t)
+	assertMessage(t, rewelcome.Type, MsgWelcome)
+	if rewelcome.Seat != 1 {
+		t.Fatalf("reseated at %d, want 1", rewelcome.Seat)
+	}
+	view := back.receiveNote(t, "Ben is back")
+	if len(view.Players[1].CardsHeld) != 2 {
+		t.Errorf("Ben can't see his hand after reconnecting")
+	}
+
+	// The game carries on as before.
+	ann.send(t, &Message{Type: MsgMove, Key: "1"})
+	back.receiveView(t, game.State{Phase: game.SelectAction, Action: game.NoAction}, 1)
+}
+
+func TestServerBotTakesOver(t *testing.T) {
+	s, _ := New(3, 2)
+	s.Grace = 10 * time.Millisecond
+	addr := serveTest(t, s)
+	ann := dialTestClient(t, addr, "Ann")
+	ann.conn.SetDeadline(time.Now().Add(15 * time.Second))
+	assertMessage(t, ann.receive(t).Type, MsgWelcome)
+	ben := dialTestClient(t, addr, "Ben")
+	assertMessage(t, ben.receive(t).Type, MsgWelcome)
+
+	ben.conn.Close()
+	ann.receiveNote(t, "A bot is playing for Ben until they return")
+	ann.send(t, &Message{Type: MsgMove, Key: "1"})
+	// Ben's turn is taken by the bot, which declares an action for him.
+	for {
+		view := ann.receive(t).View
+		if view != nil && slices.ContainsFunc(view.History, func(e game.Event) bool {
+			return e.Player == 1 && e.Kind == game.Declared
+		}) {
+			return
+		}
+	}
+}
+
+func assertMessage(t *testing.T, got, want MessageType) {
+	t.Helper()
+	if got != want {
+		t.Fatalf("got %q message, want %q", got, want)
+	}
+}
+
+func TestServerSpectators(t *testing.T) {
+	s, _ := New(3, 1)
+	s.Reveal, s.RevealDelay = true, 200*time.Millisecond
+	addr := serveTest(t, s)
+
+	watcher := dialTestClientWith(t, addr, &Message{Type: MsgHello, Watch: true})
+	welcome := watcher.receive(t)
+	assertMessage(t, welcome.Type, MsgWelcome)
+	if welcome.Seat != game.Spectator {
+		t.Fatalf("spectator seated at %d", welcome.Seat)
+	}
+	ann := dialTestClient(t, addr, "Ann")
+	assertMessage(t, ann.receive(t).Type, MsgWelcome)
+	ann.receive(t)
+	started := time.Now()
+
+	view := watcher.receiveType(t, MsgView).View
+	if held := time.Since(started); held < s.RevealDelay/2 {
+		t.Errorf("revealed view arrived after %v, want it held back", held)
+	}
+	if !view.Revealed {
+		t.Errorf("spectator view isn't revealed")
+	}
+	for _, p := range view.Players {
+		if len(p.CardsHeld) != 2 {
+			t.Errorf("spectator can't see %s's hand", p.Name)
+		}
+	}
+
+	watcher.send(t, &Message{Type: MsgMove, Key: "1"})
+	watcher.receiveType(t, MsgError)
+}
+
+func TestServerChat(t *testing.T) {
+	addr := startTestServer(t, 3, 2)
+	ann := dialTestClient(t, addr, "Ann")
+	assertMessage(t, ann.receive(t).Type, MsgWelcome)
+	ben := dialTestClient(t, addr, "Ben")
+	assertMessage(t, ben.receive(t).Type, MsgWelcome)
+
+	ann.send(t, &Message{Type: MsgChat, Text: "  Nice \x1b[2Jbluff\n"})
+	chat := ben.receiveType(t, MsgChat)
+	if chat.Name != "Ann" || chat.Text != "Nice [2Jbluff" {
+		t.Errorf("got chat %q from %q, want %q from Ann", chat.Text, chat.Name, "Nice [2Jbluff")
+	}
+
+	ann.send(t, &Message{Type: MsgChat, Text: "\x07"})
+	ann.receiveType(t, MsgError)
+}
+
+func TestJoinCleansNames(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"\x1b[31mEve\x1b[0m", "[31mEve[0m"},
+		{"  Ann\n", "Ann"},
+		{strings.Repeat("é", 30), strings.Repeat("é", MaxNameLength)},
+		{"\x1b\x07", ""},
+	}
+	for _, tc := range tests {
+		s, _ := New(3, 1)
+		_, err := s.Join(tc.name, nopSink{})
+		if tc.want == "" {
+			if err == nil {
+				t.Errorf("Join(%q) seated a player with no name", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Join(%q): %v", tc.name, err)
+			continue
+		}
+		if got := s.Info().Members[0].Name; got != tc.want {
+			t.Errorf("Join(%q) seated %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestServerStopsWhenKeysAreRefused(t *testing.T) {
+	s, _ := New(3, 1)
+	s.Mental = true
+	addr := serveTest(t, s)
+	tc := dialTestClientWith(t, addr, &Message{Type: MsgHello, Name: "Ann", Keys: true})
+	assertMessage(t, tc.receive(t).Type, MsgWelcome)
+
+	step := tc.receive(t)
+	if step.Type != MsgDeal || step.Deal == nil || step.Deal.Op != DealShuffle {
+		t.Fatalf("got %+v, want the first shuffle of the deal", step)
+	}
+	tc.send(t, &Message{Type: MsgDeal, Error: "not today"})
+	for {
+		var msg Message
+		if err := tc.dec.Decode(&msg); err != nil {
+			break
+		}
+		if msg.Type == MsgView {
+			t.Fatalf("the game was dealt without Ann's keys")
+		}
+	}
+}
+
+func TestServerSeedsFromPlayers(t *testing.T) {
+	tests := []struct {
+		name   string
+		reveal func(entropy game.Seed) string
+		dealt  bool
+	}{
+		{"honest", func(entropy game.Seed) string { return hex.EncodeToString(entropy[:]) }, true},
+		{"changed", func(game.Seed) string {
+			other := game.NewSeed()
+			return hex.EncodeToString(other[:])
+		}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			addr := startTestServer(t, 3, 1)
+			entropy := game.NewSeed()
+			cl := dialTestClientWith(t, addr, &Message{Type: MsgHello, Name: "Ann", Commitment: entropy.Commitment()})
+			assertMessage(t, cl.receive(t).Type, MsgWelcome)
+
+			ask := cl.receive(t)
+			assertMessage(t, ask.Type, MsgReveal)
+			if len(ask.Contributions) != 2 || ask.Contributions[1] != (game.Contribution{Player: 0, Commitment: entropy.Commitment()}) {
+				t.Fatalf("asked to reveal against %+v", ask.Contributions)
+			}
+			cl.send(t, &Message{Type: MsgReveal, Entropy: tc.reveal(entropy)})
+			var dealt bool
+			for {
+				var msg Message
+				if err := cl.dec.Decode(&msg); err != nil {
+					break
+				}
+				if msg.Type == MsgView {
+					dealt = true
+					break
+				}
+			}
+			if dealt != tc.dealt {
+				t.Errorf("dealt is %t, want %t", dealt, tc.dealt)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"time"
+
+	"kugo/game"
+)
+
+// spectator passes views on to someone watching the game, holding each one
+// back by delay.
+type spectator struct {
+	sink  Sink
+	delay time.Duration
+	queue chan delayed
+}
+
+type delayed struct {
+	at   time.Time
+	view *game.View
+}
+
+// How many views a spectator can fall behind by before some are dropped.
+const spectatorBacklog = 1024
+
+func newSpectator(sink Sink, delay time.Duration) *spectator {
+	sp := spectator{sink: sink, delay: delay, queue: make(chan delayed, spectatorBacklog)}
+	go sp.run()
+	return &sp
+}
+
+func (sp *spectator) push(view *game.View) {
+	select {
+	case sp.queue <- delayed{at: time.Now(), view: view}:
+	default:
+		game.Debugf("spectator has fallen behind, dropping a view")
+	}
+}
+
+// stop lets the spectator see out what is already queued, then hangs up.
+func (sp *spectator) stop() {
+	close(sp.queue)
+}
+
+func (sp *spectator) run() {
+	defer sp.sink.Close()
+	for d := range sp.queue {
+		time.Sleep(time.Until(d.at.Add(sp.delay)))
+		if err := sp.sink.Send(&Message{Type: MsgView, Seat: game.Spectator, View: d.view}); err != nil {
+			// Keep draining so nothing blocks, but there's no one to send to.
+			for range sp.queue {
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	dis "kugo/display"
+	"kugo/game"
+	inp "kugo/input"
+)
+
+// How long the victory screen stays up for SSH players who don't press a key.
+const sshLinger = 30 * time.Second
+
+// ServeSSH is Serve for players connecting with a plain ssh client. Each
+// session is seated under its SSH user name and drawn by the same Display as
+// a local game, so nothing needs installing on the players' side.
+//
+// Anyone can log in under any name, so a dropped player gets their seat back
+// only with the reconnect code they were shown when seated. They can send it
+// as the KUGO_TOKEN environment variable, or type it in when asked.
+func (s *Server) ServeSSH(ctx context.Context, ln net.Listener, config *ssh.ServerConfig) error {
+	return s.serveListener(ctx, ln, func(ctx context.Context, conn net.Conn) {
+		s.handleSSH(ctx, conn, config)
+	})
+}
+
+func (s *Server) handleSSH(ctx context.Context, conn net.Conn, config *ssh.ServerConfig) {
+	sconn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		game.Debugf("ssh handshake with %s failed: %v", conn.RemoteAddr(), err)
+		conn.Close()
+		return
+	}
+	defer sconn.Close()
+	go ssh.DiscardRequests(reqs)
+	for newChan := range chans {
+		if newChan.ChannelType() != "session" {
+			newChan.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+		ch, requests, err := newChan.Accept()
+		if err != nil {
+			continue
+		}
+		go s.handleSession(ctx, sconn.User(), ch, requests)
+	}
+}
+
+// sshSession is the Sink for a player connected over SSH.
+type sshSession struct {
+	display *dis.Display
+	mu      sync.Mutex
+	phase   game.Phase
+	drawing bool
+	draw    func()
+	over    chan struct{}
+	once    sync.Once
+	chat    []string
+	token   string
+}
+
+// sshTokenEnv is the environment variable a reconnecting player can send
+// their reconnect code in.
+const sshTokenEnv = "KUGO_TOKEN"
+
+// sshChatLines is how much of the chat a session keeps on screen.
+const sshChatLines = 8
+
+func (sess *sshSession) Send(msg *Message) error {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if msg.Type == MsgWelcome {
+		sess.token = msg.Token
+		return nil
+	}
+	if msg.Type == MsgChat {
+		sess.chat = append(sess.chat, fmt.Sprintf("%s: %s", msg.Name, msg.Text))
+		sess.chat = sess.chat[max(0, len(sess.chat)-sshChatLines):]
+		sess.display.UpdateChat(sess.chat)
+		return nil
+	}
+	if msg.Type != MsgView {
+		return nil
+	}
+	sess.display.UpdateDisplay(msg.View.DisplayData())
+	sess.phase = msg.View.Phase
+	if !sess.drawing {
+		sess.drawing = true
+		sess.draw()
+	}
+	return nil
+}
+
+// Close tells the session the game is over. The session itself stays open
+// until the player has seen how it ended.
+func (sess *sshSession) Close() error {
+	sess.once.Do(func() { close(sess.over) })
+	return nil
+}
+
+func (s *Server) handleSession(ctx context.Context, user string, ch ssh.Channel, requests <-chan *ssh.Request) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Room for a quit and the read error that follows it, so the input
+	// stream never blocks once the session has stopped listening.
+	sessErr := make(chan error, 2)
+	display := dis.NewDisplayTo(ch, sessErr)
+
+	// Agree to whatever the terminal asks for, keeping the display the size
+	// of the player's window, and wait for the shell before drawing anything.
+	shell := make(chan struct{})
+	var token string
+	go func() {
+		var started bool
+		for req := range requests {
+			switch req.Type {
+			case "pty-req":
+				var pty struct {
+					Term                      string
+					Cols, Rows, Width, Height uint32
+					Modes                     string
+				}
+				if ssh.Unmarshal(req.Payload, &pty) == nil {
+					display.Resize(int(pty.Cols), int(pty.Rows))
+				}
+				req.Reply(true, nil)
+			case "window-change":
+				var size struct{ Cols, Rows, Width, Height uint32 }
+				if ssh.Unmarshal(req.Payload, &size) == nil {
+					display.Resize(int(size.Cols), int(size.Rows))
+				}
+				req.Reply(true, nil)
+			case "env":
+				var env struct{ Name, Value string }
+				if ssh.Unmarshal(req.Payload, &env) == nil && env.Name == sshTokenEnv && !started {
+					token = env.Value
+				}
+				req.Reply(true, nil)
+			case "shell":
+				req.Reply(!started, nil)
+				if !started {
+					started = true
+					close(shell)
+				}
+			default:
+				req.Reply(false, nil)
+			}
+		}
+	}()
+	select {
+	case <-shell:
+	case <-ctx.Done():
+		ch.Close()
+		return
+	}
+
+	drawCtx, stopDrawing := context.WithCancel(ctx)
+	drawDone := make(chan struct{})
+	sess := sshSession{
+		display: display,
+		over:    make(chan struct{}),
+	}
+	sess.draw = func() {
+		go func() {
+			defer close(drawDone)
+			sess.display.DrawDisplay(drawCtx)
+		}()
+	}
+	status := uint32(0)
+	defer func() {
+		stopDrawing()
+		sess.mu.Lock()
+		if sess.drawing {
+			<-drawDone
+		}
+		sess.mu.Unlock()
+		fmt.Fprint(ch, dis.Restore)
+		ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+		ch.Close()
+	}()
+
+	fmt.Fprint(ch, dis.Reset)
+	dec := inp.NewDecoder(ch)
+	seatIdx, err := s.seatSSH(user, token, dec, &sess)
+	if err != nil {
+		sess.display.DrawMessage(fmt.Sprintf("Can't seat %s: %v", user, err))
+		status = 1
+		return
+	}
+	sess.mu.Lock()
+	code := sess.token
+	sess.chat = append(sess.chat, "Your reconnect code is "+code)
+	sess.mu.Unlock()
+	sess.display.DrawList("Seated! Waiting for the other players to join...", []string{
+		"Your reconnect code is " + code,
+	}, []string{
+		"if you lose your connection, ssh back in and enter it when asked",
+		fmt.Sprintf("or send it with: ssh -o SetEnv=%s=%s", sshTokenEnv, code),
+	})
+
+	keys := make(chan rune)
+	inputHandler := inp.NewInputHandler(nil, sessErr)
+	inputHandler.SetMenu(sess.display)
+	go inputHandler.CreateInputStream(ctx, dec, keys)
+	sess.mu.Lock()
+	sess.display.UpdateChat(sess.chat)
+	sess.mu.Unlock()
+	var entry inp.ChatEntry
+
+	var over <-chan struct{} = sess.over
+	var linger <-chan time.Time
+	for {
+		select {
+		case key := <-keys:
+			if linger != nil {
+				return
+			}
+			line, handled := entry.Press(key)
+			inputHandler.SetTyping(entry.Typing || entry.Emoting)
+			sess.mu.Lock()
+			if entry.Emoting {
+				sess.display.UpdateChatEntry(false, "", inp.Emotes)
+			} else {
+				sess.display.UpdateChatEntry(entry.Typing, entry.Text(), nil)
+			}
+			sess.mu.Unlock()
+			if line != "" {
+				if err := s.Say(seatIdx, line); err != nil {
+					game.Debugf("%s: %v", user, err)
+				}
+			}
+			if handled || key < '0' || key > '9' {
+				continue
+			}
+			if err := s.Press(ctx, seatIdx, key); err != nil && !errors.Is(err, context.Canceled) {
+				game.Debugf("%s: %v", user, err)
+			}
+		case err := <-sessErr:
+			if linger == nil {
+				s.Leave(seatIdx, &sess, err)
+			}
+			return
+		case <-over:
+			over = nil
+			linger = time.After(sshLinger)
+			sess.mu.Lock()
+			if sess.phase != game.EndGame {
+				stopDrawing()
+				if sess.drawing {
+					<-drawDone
+				}
+				sess.display.DrawMessage("The game was called off. Press any key to leave.")
+			}
+			sess.mu.Unlock()
+		case <-linger:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// seatSSH seats a session under user, or gives them back their seat if they
+// have its reconnect code. Someone whose name is already taken is asked for
+// the code, as they are most likely coming back after losing their
+// connection.
+func (s *Server) seatSSH(user, token string, dec *inp.Decoder, sess *sshSession) (int, error) {
+	user = cleanName(user)
+	if token != "" {
+		return s.Rejoin(token, sess)
+	}
+	idx, err := s.Join(user, sess)
+	if err == nil || !s.seated(user) {
+		return idx, err
+	}
+	var line inp.Line
+	for {
+		sess.display.DrawMessage(fmt.Sprintf("%s is already seated. Enter its reconnect code: %s", user, line.String()))
+		ev, err := dec.Next()
+		if err != nil {
+			return 0, err
+		}
+		if ev.Key == inp.KeyEscape {
+			return 0, errors.New("no reconnect code given")
+		}
+		if line.Press(ev) {
+			return s.Rejoin(strings.TrimSpace(line.String()), sess)
+		}
+	}
+}
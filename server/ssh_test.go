@@ -0,0 +1,179 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"net"
+	"regexp"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func startTestSSHServer(t *testing.T, players, humans int) string {
+	t.Helper()
+	s, err := New(players, humans)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("host key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("host key: %v", err)
+	}
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go s.ServeSSH(ctx, ln, config)
+	return ln.Addr().String()
+}
+
+// readUntil reads from the session's output until want has appeared, and
+// returns what it read.
+func readUntil(t *testing.T, out <-chan []byte, want string) []byte {
+	t.Helper()
+	var seen []byte
+	timeout := time.After(5 * time.Second)
+	for !bytes.Contains(seen, []byte(want)) {
+		select {
+		case chunk, ok := <-out:
+			if !ok {
+				t.Fatalf("session ended before %q was drawn", want)
+			}
+			seen = append(seen, chunk...)
+		case <-timeout:
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+	return seen
+}
+
+// openSSH starts a shell as user, with env set, and returns its input and
+// output.
+func openSSH(t *testing.T, addr, user string, env map[string]string) (io.Writer, <-chan []byte, *ssh.Client) {
+	t.Helper()
+	conn, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            user,
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	session, err := conn.NewSession()
+	if err != nil {
+		t.Fatalf("session: %v", err)
+	}
+	for name, value := range env {
+		if err := session.Setenv(name, value); err != nil {
+			t.Fatalf("setenv: %v", err)
+		}
+	}
+	if err := session.RequestPty("xterm", 40, 120, ssh.TerminalModes{}); err != nil {
+		t.Fatalf("pty: %v", err)
+	}
+	stdin, _ := session.StdinPipe()
+	stdout, _ := session.StdoutPipe()
+	if err := session.Shell(); err != nil {
+		t.Fatalf("shell: %v", err)
+	}
+	out := make(chan []byte)
+	go func() {
+		defer close(out)
+		buf := make([]byte, 4096)
+		for {
+			n, err := stdout.Read(buf)
+			if err != nil {
+				return
+			}
+			out <- bytes.Clone(buf[:n])
+		}
+	}()
+	return stdin, out, conn
+}
+
+func TestSSHSessionPlaysGame(t *testing.T) {
+	addr := startTestSSHServer(t, 3, 1)
+	conn, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            "Ann",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	session, err := conn.NewSession()
+	if err != nil {
+		t.Fatalf("session: %v", err)
+	}
+	defer session.Close()
+	if err := session.RequestPty("xterm", 40, 120, ssh.TerminalModes{}); err != nil {
+		t.Fatalf("pty: %v", err)
+	}
+	stdin, _ := session.StdinPipe()
+	stdout, _ := session.StdoutPipe()
+	if err := session.Shell(); err != nil {
+		t.Fatalf("shell: %v", err)
+	}
+
+	out := make(chan []byte)
+	go func() {
+		defer close(out)
+		buf := make([]byte, 4096)
+		for {
+			n, err := stdout.Read(buf)
+			if err != nil {
+				return
+			}
+			out <- bytes.Clone(buf[:n])
+		}
+	}()
+
+	// The game starts as soon as Ann is seated, and it is her turn first.
+	readUntil(t, out, "Income")
+	stdin.Write([]byte("1"))
+	readUntil(t, out, "has selected")
+
+	stdin.Write([]byte("q"))
+	done := make(chan error)
+	go func() { done <- session.Wait() }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("session still open after quitting")
+	}
+}
+
+func TestSSHReconnectNeedsCode(t *testing.T) {
+	addr := startTestSSHServer(t, 3, 1)
+	_, out, conn := openSSH(t, addr, "Ann", nil)
+	seen := readUntil(t, out, "Income")
+	m := regexp.MustCompile(`reconnect code is ([A-Z2-7]{26})`).FindSubmatch(seen)
+	if m == nil {
+		t.Fatalf("Ann was never shown a reconnect code")
+	}
+	conn.Close()
+
+	// Anyone can log in as Ann, but without the code they don't get her seat.
+	stdin, out, _ := openSSH(t, addr, "Ann", nil)
+	readUntil(t, out, "Enter its reconnect code")
+	stdin.Write([]byte("WRONG\r"))
+	readUntil(t, out, "Can't seat Ann")
+
+	_, out, _ = openSSH(t, addr, "Ann", map[string]string{sshTokenEnv: string(m[1])})
+	readUntil(t, out, "Income")
+}
@@ -0,0 +1,268 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"kugo/game"
+)
+
+// client is one remote player's TCP connection. Messages for it are queued
+// and written by a goroutine of its own, so sending to a slow client never
+// holds up the game.
+type client struct {
+	conn    net.Conn
+	dec     *json.Decoder
+	enc     *json.Encoder
+	out     chan *Message
+	closing chan struct{}
+	once    sync.Once
+	// keys is whether the client holds its own keys for a mental poker
+	// deal, and committed the hash of its entropy for the seed, as it said
+	// in its hello.
+	keys      bool
+	committed string
+}
+
+// sendBacklog is how many messages a client can fall behind by before it is
+// dropped.
+const sendBacklog = 256
+
+// writeTimeout is how long a client has to take each message before it is
+// dropped.
+const writeTimeout = 10 * time.Second
+
+var errClosed = errors.New("connection closed")
+
+func newClient(conn net.Conn) *client {
+	cl := client{
+		conn:    conn,
+		dec:     json.NewDecoder(bufio.NewReader(conn)),
+		enc:     json.NewEncoder(conn),
+		out:     make(chan *Message, sendBacklog),
+		closing: make(chan struct{}),
+	}
+	go cl.write()
+	return &cl
+}
+
+// write sends the client its messages in order until it is closed, when it
+// sends whatever is still queued and hangs up.
+func (cl *client) write() {
+	defer cl.conn.Close()
+	for {
+		select {
+		case msg := <-cl.out:
+			if !cl.encode(msg) {
+				return
+			}
+		case <-cl.closing:
+			for {
+				select {
+				case msg := <-cl.out:
+					if !cl.encode(msg) {
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (cl *client) encode(msg *Message) bool {
+	cl.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := cl.enc.Encode(msg); err != nil {
+		game.Debugf("writing to %s: %v", cl.conn.RemoteAddr(), err)
+		return false
+	}
+	return true
+}
+
+// send queues msg for the client. A client too far behind to take it is
+// dropped, which its reader sees as the connection failing.
+func (cl *client) send(msg *Message) error {
+	select {
+	case <-cl.closing:
+		return errClosed
+	default:
+	}
+	select {
+	case cl.out <- msg:
+		return nil
+	default:
+		game.Debugf("%s has fallen behind, dropping it", cl.conn.RemoteAddr())
+		cl.conn.Close()
+		return errors.New("client has fallen behind")
+	}
+}
+
+func (cl *client) sendError(format string, args ...any) error {
+	return cl.send(&Message{Type: MsgError, Error: fmt.Sprintf(format, args...)})
+}
+
+func (cl *client) Send(msg *Message) error {
+	return cl.send(msg)
+}
+
+func (cl *client) holdsKeys() bool {
+	return cl.keys
+}
+
+func (cl *client) commitment() string {
+	return cl.committed
+}
+
+// Close hangs up once everything already sent has been written.
+func (cl *client) Close() error {
+	cl.once.Do(func() { close(cl.closing) })
+	return nil
+}
+
+// Serve accepts TCP clients on ln until every human seat is filled, then runs
+// the game to the end. It closes ln when it returns.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	return s.serveListener(ctx, ln, s.handleTCP)
+}
+
+// serveListener hands each connection on ln to handle while the game runs.
+func (s *Server) serveListener(ctx context.Context, ln net.Listener, handle func(context.Context, net.Conn)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go accept(ctx, ln, handle)
+	return s.Run(ctx)
+}
+
+// accept hands each connection on ln to its own handle goroutine until ctx is
+// done, then closes ln.
+func accept(ctx context.Context, ln net.Listener, handle func(context.Context, net.Conn)) {
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go handle(ctx, conn)
+	}
+}
+
+// handleTCP speaks the JSON protocol with a single client for as long as the
+// connection lasts.
+func (s *Server) handleTCP(ctx context.Context, conn net.Conn) {
+	cl := newClient(conn)
+	var hello Message
+	if err := cl.dec.Decode(&hello); err != nil || hello.Type != MsgHello {
+		cl.sendError("expected a hello message")
+		cl.Close()
+		return
+	}
+	if hello.Watch {
+		s.watchTCP(cl)
+		return
+	}
+	if err := cl.greet(&hello); err != nil {
+		cl.sendError("%v", err)
+		cl.Close()
+		return
+	}
+	var seatIdx int
+	var err error
+	if hello.Token != "" {
+		seatIdx, err = s.Rejoin(hello.Token, cl)
+	} else {
+		seatIdx, err = s.Join(hello.Name, cl)
+	}
+	if err != nil {
+		cl.sendError("%v", err)
+		cl.Close()
+		return
+	}
+	defer cl.Close()
+	// Moves wait for the game to take them, which it may not do while it is
+	// waiting on this client for a step of the deal. They are passed on
+	// from a goroutine of their own so that the answer can still be read.
+	moves := make(chan rune, 1)
+	defer close(moves)
+	go func() {
+		for key := range moves {
+			if err := s.Press(ctx, seatIdx, key); err != nil {
+				cl.sendError("%v", err)
+			}
+		}
+	}()
+	for {
+		var msg Message
+		if err := cl.dec.Decode(&msg); err != nil {
+			s.Leave(seatIdx, cl, err)
+			return
+		}
+		if msg.Type == MsgChat {
+			if err := s.Say(seatIdx, msg.Text); err != nil {
+				cl.sendError("%v", err)
+			}
+			continue
+		}
+		if msg.Type == MsgDeal || msg.Type == MsgReveal {
+			if err := s.answer(seatIdx, &msg); err != nil {
+				cl.sendError("%v", err)
+			}
+			continue
+		}
+		if msg.Type != MsgMove {
+			cl.sendError("unexpected %q message", msg.Type)
+			continue
+		}
+		keys := []rune(msg.Key)
+		if len(keys) != 1 {
+			cl.sendError("key %q is not a single digit", msg.Key)
+			continue
+		}
+		select {
+		case moves <- keys[0]:
+		default:
+			cl.sendError("a move is already waiting for the game to take it")
+		}
+	}
+}
+
+// greet takes what a player's hello says about holding keys for the deal and
+// the entropy they are committed to.
+func (cl *client) greet(hello *Message) error {
+	if hello.Commitment != "" {
+		if sum, err := hex.DecodeString(hello.Commitment); err != nil || len(sum) != sha256.Size {
+			return errors.New("a commitment is a SHA-256 hash in hex")
+		}
+	}
+	cl.keys, cl.committed = hello.Keys, hello.Commitment
+	return nil
+}
+
+// watchTCP keeps a spectator's connection open until they hang up.
+func (s *Server) watchTCP(cl *client) {
+	defer cl.Close()
+	if err := s.Watch(cl); err != nil {
+		cl.sendError("%v", err)
+		cl.Close()
+		return
+	}
+	defer s.Unwatch(cl)
+	for {
+		var msg Message
+		if err := cl.dec.Decode(&msg); err != nil {
+			return
+		}
+		cl.sendError("spectators can't send %q messages", msg.Type)
+	}
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestServeUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kugo.sock")
+	// Leave a stale socket behind, as a host that crashed would.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	ln, err := ListenUnix(path)
+	if err != nil {
+		t.Fatalf("ListenUnix over a stale socket: %v", err)
+	}
+	if _, err := ListenUnix(path); err == nil {
+		t.Errorf("ListenUnix took over a live socket")
+	}
+	s, _ := New(3, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go s.Serve(ctx, ln)
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	tc := testClient{conn: conn, dec: json.NewDecoder(conn), enc: json.NewEncoder(conn)}
+	tc.send(t, &Message{Type: MsgHello, Name: "Ann"})
+	assertMessage(t, tc.receive(t).Type, MsgWelcome)
+	assertMessage(t, tc.receive(t).Type, MsgView)
+}
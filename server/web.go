@@ -0,0 +1,246 @@
+package server
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	dis "kugo/display"
+	"kugo/game"
+)
+
+//go:embed web
+var webFiles embed.FS
+
+// webEvent is a Message as the browser client is sent it. Views are swapped
+// for the display Model of the screen they make, so the page only has to lay
+// it out.
+type webEvent struct {
+	Type   MessageType `json:"type"`
+	Name   string      `json:"name,omitempty"`
+	Seat   int         `json:"seat"`
+	Token  string      `json:"token,omitempty"`
+	Error  string      `json:"error,omitempty"`
+	Text   string      `json:"text,omitempty"`
+	Room   *RoomInfo   `json:"room,omitempty"`
+	Screen *dis.Model  `json:"screen,omitempty"`
+}
+
+// webClient is one browser's stream of server-sent events. Like a TCP
+// client, it queues what it is sent, and the request's handler writes it out.
+type webClient struct {
+	out    chan []byte
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newWebClient() *webClient {
+	return &webClient{out: make(chan []byte, sendBacklog), closed: make(chan struct{})}
+}
+
+func (wc *webClient) Send(msg *Message) error {
+	ev := webEvent{
+		Type:  msg.Type,
+		Name:  msg.Name,
+		Seat:  msg.Seat,
+		Token: msg.Token,
+		Error: msg.Error,
+		Text:  msg.Text,
+		Room:  msg.Room,
+	}
+	if msg.View != nil {
+		ev.Screen = dis.NewModel(msg.View.DisplayData()).Plain()
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-wc.closed:
+		return errClosed
+	default:
+	}
+	select {
+	case wc.out <- data:
+		return nil
+	default:
+		game.Debugf("a browser has fallen behind, dropping it")
+		wc.Close()
+		return errors.New("client has fallen behind")
+	}
+}
+
+func (wc *webClient) sendError(format string, args ...any) error {
+	return wc.Send(&Message{Type: MsgError, Error: fmt.Sprintf(format, args...)})
+}
+
+// Close ends the stream once everything already sent has been written.
+func (wc *webClient) Close() error {
+	wc.once.Do(func() { close(wc.closed) })
+	return nil
+}
+
+// stream writes the client's events to w until it is closed, or returns why
+// the browser was lost. Each write has writeTimeout to go through.
+func (wc *webClient) stream(ctx context.Context, w http.ResponseWriter) error {
+	rc := http.NewResponseController(w)
+	// The request was read long ago, and a stream lasts as long as the game.
+	rc.SetReadDeadline(time.Time{})
+	write := func(data []byte) error {
+		rc.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+	for {
+		select {
+		case data := <-wc.out:
+			if err := write(data); err != nil {
+				wc.Close()
+				return err
+			}
+		case <-wc.closed:
+			for {
+				select {
+				case data := <-wc.out:
+					if write(data) != nil {
+						return nil
+					}
+				default:
+					return nil
+				}
+			}
+		case <-ctx.Done():
+			wc.Close()
+			return ctx.Err()
+		}
+	}
+}
+
+// newHTTPServer serves h with timeouts, so that a client that stalls can't
+// hold a connection open. Event streams lift them for themselves.
+func newHTTPServer(h http.Handler) *http.Server {
+	return &http.Server{
+		Handler:           h,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+}
+
+// ServeWeb is Serve for players using a browser. It serves the page for the
+// web client on ln, and runs the game once every human seat is filled.
+func (s *Server) ServeWeb(ctx context.Context, ln net.Listener) error {
+	hs := newHTTPServer(s.WebHandler())
+	go hs.Serve(ln)
+	defer hs.Close()
+	return s.Run(ctx)
+}
+
+// WebHandler serves the web client and the endpoints it plays through:
+//
+//	GET  /events?name=Bob    takes a seat and streams events
+//	GET  /events?token=...   takes the seat back after a lost connection
+//	GET  /events?watch=1     streams events to a spectator
+//	POST /move  token, key   presses a key for the seat
+//	POST /chat  token, text  says something to the table
+//
+// Events are server-sent, each a Message as JSON but with the view replaced by
+// the display Model of the screen it makes. Moves go through Press, so they
+// are checked exactly as a terminal player's keys are.
+func (s *Server) WebHandler() http.Handler {
+	static, _ := fs.Sub(webFiles, "web")
+	mux := http.NewServeMux()
+	mux.Handle("GET /", http.FileServerFS(static))
+	mux.HandleFunc("GET /events", s.handleEvents)
+	mux.HandleFunc("POST /move", s.handleMove)
+	mux.HandleFunc("POST /chat", s.handleChat)
+	return mux
+}
+
+// handleEvents seats or welcomes back a browser, then streams events to it
+// until either side hangs up.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if _, ok := w.(http.Flusher); !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	wc := newWebClient()
+	q := r.URL.Query()
+	if q.Get("watch") != "" {
+		if err := s.Watch(wc); err != nil {
+			wc.sendError("%v", err)
+			wc.Close()
+		} else {
+			defer s.Unwatch(wc)
+		}
+		wc.stream(r.Context(), w)
+		return
+	}
+	var seatIdx int
+	var err error
+	if token := q.Get("token"); token != "" {
+		seatIdx, err = s.Rejoin(token, wc)
+	} else {
+		seatIdx, err = s.Join(q.Get("name"), wc)
+	}
+	if err != nil {
+		wc.sendError("%v", err)
+		wc.Close()
+		wc.stream(r.Context(), w)
+		return
+	}
+	if err := wc.stream(r.Context(), w); err != nil {
+		s.Leave(seatIdx, wc, err)
+	}
+}
+
+// webSeat is the seat of the player whose token came with r.
+func (s *Server) webSeat(r *http.Request) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokenSeat(r.FormValue("token"))
+}
+
+func (s *Server) handleMove(w http.ResponseWriter, r *http.Request) {
+	seatIdx, err := s.webSeat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	key := r.FormValue("key")
+	keys := []rune(key)
+	if len(keys) != 1 {
+		http.Error(w, fmt.Sprintf("key %q is not a single digit", key), http.StatusBadRequest)
+		return
+	}
+	if err := s.Press(r.Context(), seatIdx, keys[0]); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	seatIdx, err := s.webSeat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err := s.Say(seatIdx, r.FormValue("text")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,133 @@
+// The kugo web client. It lays out the screens the server streams to it and
+// posts the keys the player picks back, so it knows nothing about the rules.
+"use strict";
+
+const $ = (id) => document.getElementById(id);
+const chatLines = 8;
+let token = sessionStorage.getItem("kugo-token");
+let events = null;
+let chat = [];
+
+function connect(query) {
+  events = new EventSource("/events?" + new URLSearchParams(query));
+  events.onmessage = (e) => receive(JSON.parse(e.data));
+  events.onerror = () => {
+    $("status").textContent = "Connection lost, trying again...";
+    if (token) {
+      // Come back to the same seat rather than asking for a new one.
+      events.close();
+      setTimeout(() => connect({ token }), 1000);
+    }
+  };
+}
+
+function receive(msg) {
+  switch (msg.type) {
+  case "welcome":
+    $("join").classList.add("hidden");
+    $("game").classList.remove("hidden");
+    if (msg.seat >= 0) {
+      token = msg.token;
+      sessionStorage.setItem("kugo-token", token);
+      $("say").classList.remove("hidden");
+      $("status").textContent = "Waiting for the other players...";
+    } else {
+      $("status").textContent = "Spectating";
+    }
+    break;
+  case "view":
+    $("status").textContent = "";
+    draw(msg.screen);
+    if (msg.screen.over) {
+      sessionStorage.removeItem("kugo-token");
+      events.close();
+    }
+    break;
+  case "chat":
+    chat.push(msg.name + ": " + msg.text);
+    chat = chat.slice(-chatLines);
+    $("chat").replaceChildren(...chat.map(line));
+    break;
+  case "error":
+    $("status").textContent = msg.error;
+    if (!$("game").classList.contains("hidden")) {
+      break;
+    }
+    // Turned away before getting a seat.
+    events.close();
+    sessionStorage.removeItem("kugo-token");
+    token = null;
+    break;
+  }
+}
+
+function line(text) {
+  const div = document.createElement("div");
+  div.textContent = text;
+  return div;
+}
+
+function draw(screen) {
+  $("players").replaceChildren(...screen.players.map((p) => {
+    const tr = document.createElement("tr");
+    tr.classList.toggle("current", p.current);
+    tr.classList.toggle("out", !p.alive);
+    tr.classList.toggle("local", p.local);
+    const coins = p.alive ? String(p.coins) : "~ELIMINATED~";
+    for (const text of [p.name, coins, p.hand, p.claims]) {
+      const td = document.createElement("td");
+      td.textContent = text;
+      tr.append(td);
+    }
+    return tr;
+  }));
+  $("log").replaceChildren(...screen.log.map(line));
+  $("hand").textContent = screen.hand.length ? "Your hand: [" + screen.hand.join(" | ") + "]" : "";
+  if (screen.over) {
+    $("prompt").textContent = "The game is over, and " + screen.victor + " is the victor!";
+    $("options").replaceChildren();
+    return;
+  }
+  $("prompt").textContent = screen.prompt;
+  $("options").replaceChildren(...screen.options.map((o) => {
+    const button = document.createElement("button");
+    button.textContent = "[" + o.key + "] " + o.label;
+    button.onclick = () => press(String(o.key));
+    return button;
+  }));
+}
+
+function post(path, body) {
+  fetch(path, { method: "POST", body: new URLSearchParams({ token, ...body }) })
+    .then(async (res) => {
+      if (!res.ok) {
+        $("status").textContent = (await res.text()).trim();
+      }
+    });
+}
+
+function press(key) {
+  if (token) {
+    post("/move", { key });
+  }
+}
+
+$("join").onsubmit = (e) => {
+  e.preventDefault();
+  connect({ name: $("name").value });
+};
+$("watch").onclick = () => connect({ watch: 1 });
+$("say").onsubmit = (e) => {
+  e.preventDefault();
+  post("/chat", { text: $("text").value });
+  $("text").value = "";
+};
+document.onkeydown = (e) => {
+  if (e.target.tagName !== "INPUT" && /^[0-9]$/.test(e.key)) {
+    press(e.key);
+  }
+};
+
+if (token) {
+  connect({ token });
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// webEvents reads server-sent events from a stream.
+type webEvents struct {
+	scanner *bufio.Scanner
+}
+
+func (we *webEvents) receive(t *testing.T) *webEvent {
+	t.Helper()
+	for we.scanner.Scan() {
+		data, ok := strings.CutPrefix(we.scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var ev webEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			t.Fatalf("decode event: %v", err)
+		}
+		return &ev
+	}
+	t.Fatalf("stream ended: %v", we.scanner.Err())
+	return nil
+}
+
+func startWebServer(t *testing.T, players, humans int) string {
+	t.Helper()
+	s, err := New(players, humans)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go s.ServeWeb(ctx, ln)
+	return "http://" + ln.Addr().String()
+}
+
+func TestWebPlaysIncome(t *testing.T) {
+	base := startWebServer(t, 3, 1)
+	client := http.Client{Timeout: 10 * time.Second}
+
+	page, err := client.Get(base + "/")
+	if err != nil {
+		t.Fatalf("GET /: %v", err)
+	}
+	body, _ := io.ReadAll(page.Body)
+	page.Body.Close()
+	if !strings.Contains(string(body), "app.js") {
+		t.Errorf("the page doesn't load the client")
+	}
+
+	resp, err := client.Get(base + "/events?name=Ann")
+	if err != nil {
+		t.Fatalf("GET /events: %v", err)
+	}
+	defer resp.Body.Close()
+	events := webEvents{bufio.NewScanner(resp.Body)}
+	welcome := events.receive(t)
+	assertMessage(t, welcome.Type, MsgWelcome)
+
+	view := events.receive(t)
+	assertMessage(t, view.Type, MsgView)
+	screen := view.Screen
+	if len(screen.Players) != 3 || len(screen.Options) != 7 || len(screen.Hand) != 2 {
+		t.Fatalf("got %d players, %d options and %d cards in hand, want 3, 7 and 2", len(screen.Players), len(screen.Options), len(screen.Hand))
+	}
+	if strings.Contains(screen.Hand[0]+screen.Options[3].Label, "\033") {
+		t.Errorf("the screen has terminal colour codes in it")
+	}
+
+	move := func(token, key string) int {
+		resp, err := client.PostForm(base+"/move", url.Values{"token": {token}, "key": {key}})
+		if err != nil {
+			t.Fatalf("POST /move: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := move("wrong", "1"); code != http.StatusForbidden {
+		t.Errorf("move with a bad token: got %d, want %d", code, http.StatusForbidden)
+	}
+	if code := move(welcome.Token, "1"); code != http.StatusNoContent {
+		t.Fatalf("move: got %d, want %d", code, http.StatusNoContent)
+	}
+	for {
+		ev := events.receive(t)
+		if ev.Type == MsgView && ev.Screen.Players[0].Coins == 3 {
+			return
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"kugo/game"
+)
+
+func runVerify(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: kugo verify RECORD")
+	}
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	var record game.ShuffleRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return fmt.Errorf("reading %s: %w", args[0], err)
+	}
+	if err := game.VerifyShuffle(&record); err != nil {
+		return fmt.Errorf("the deal doesn't check out: %w", err)
+	}
+	fmt.Printf("All %d cards drawn and returned match the seed committed to as %s\n", len(record.Events), record.Commitment)
+	return nil
+}
+
+// checkDeal checks the record a hosted game ends with against the commitment
+// it started with and the cards the witness saw dealt, and saves it for
+// running through verify again. It returns a line saying how it went.
+func checkDeal(commitment string, witness *game.Witness, record *game.ShuffleRecord) string {
+	if record.Commitment != commitment {
+		return "Warning: the host changed its deck commitment during the game!"
+	}
+	if err := game.VerifyShuffle(record); err != nil {
+		return fmt.Sprintf("Warning: the deal doesn't check out: %v", err)
+	}
+	if err := witness.Check(record); err != nil {
+		return fmt.Sprintf("Warning: the record isn't the deal that was played: %v", err)
+	}
+	path := fmt.Sprintf("kugo-%s.json", commitment[:12])
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err == nil {
+		err = os.WriteFile(path, data, 0o644)
+	}
+	if err != nil {
+		game.Debugf("saving the shuffle record: %v", err)
+		return "The deal checks out against the host's commitment."
+	}
+	return fmt.Sprintf("The deal checks out; check it yourself with: kugo verify %s", path)
+}